@@ -2,13 +2,18 @@ package main
 
 import (
 	"archive/zip"
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -17,6 +22,33 @@ type ExportZipRequest struct {
 	Mp3Path string `json:"mp3_path"`
 	LrcPath string `json:"lrc_path"`
 	MixName string `json:"mix_name,omitempty"` // Base name for the zip and contents
+
+	// Format selects the container/codec to transcode Mp3Path into before
+	// zipping: "mp3" (default, no transcode), "flac", "opus", "m4a-aac",
+	// "m4a-alac". BitrateKbps applies to the lossy codecs (opus, m4a-aac);
+	// FlacCompressionLevel (0-8) applies to flac only.
+	Format               string `json:"format,omitempty"`
+	BitrateKbps          int    `json:"bitrate_kbps,omitempty"`
+	FlacCompressionLevel int    `json:"flac_compression_level,omitempty"`
+
+	// Embed switches from zipping mp3+lrc side by side to writing a single
+	// ID3v2.4-tagged MP3 (lyrics, cover art, chapters, BPM/key/gain all
+	// embedded) — see handleExportEmbedded. Only valid with Format "" or
+	// "mp3", since ID3v2 embedding assumes an MP3 container.
+	Embed          bool             `json:"embed,omitempty"`
+	CoverImagePath string           `json:"cover_image_path,omitempty"`
+	Chapters       []Chapter        `json:"chapters,omitempty"`
+	TrackTags      []ExportTrackTag `json:"track_tags,omitempty"`
+}
+
+// ExportTrackTag carries the per-track metadata embedded as TXXX frames in
+// embed mode: BPM, detected key, and the EBU R128 gain RenderFinalMix
+// applied to that track going into the mix.
+type ExportTrackTag struct {
+	Title  string  `json:"title"`
+	BPM    float64 `json:"bpm"`
+	Key    string  `json:"key"`
+	GainDB float64 `json:"gain_db"`
 }
 
 // handleExportZip packages the provided files into a ZIP and sends it as response
@@ -43,6 +75,31 @@ func handleExportZip(w http.ResponseWriter, r *http.Request) {
 		safeName = safeName[:len(safeName)-len(ext)]
 	}
 
+	if req.Embed && req.Format != "" && req.Format != "mp3" {
+		http.Error(w, "embed mode only supports mp3 output", http.StatusBadRequest)
+		return
+	}
+
+	audioPath := req.Mp3Path
+	if req.Format != "" && req.Format != "mp3" {
+		transcoded, err := transcodeAudio(req.Mp3Path, req.Format, req.BitrateKbps, req.FlacCompressionLevel, cacheDir, nil)
+		if err != nil {
+			log.Printf("Failed to transcode to %s: %v", req.Format, err)
+			http.Error(w, "Failed to transcode: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(transcoded)
+		audioPath = transcoded
+	}
+
+	if req.Embed {
+		if err := handleExportEmbedded(w, req, audioPath, safeName); err != nil {
+			log.Printf("Failed to embed tags: %v", err)
+			http.Error(w, "Failed to embed tags: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
 	// Prepare ZIP response
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/zip")
@@ -51,9 +108,9 @@ func handleExportZip(w http.ResponseWriter, r *http.Request) {
 	zw := zip.NewWriter(w)
 	defer zw.Close()
 
-	if err := addFileToZip(zw, req.Mp3Path, safeName+".mp3"); err != nil {
-		log.Printf("Failed to zip mp3: %v", err)
-		http.Error(w, "Failed to zip mp3", http.StatusInternalServerError)
+	if err := addFileToZip(zw, audioPath, safeName+exportFormatExt(req.Format)); err != nil {
+		log.Printf("Failed to zip audio: %v", err)
+		http.Error(w, "Failed to zip audio", http.StatusInternalServerError)
 		return
 	}
 	if err := addFileToZip(zw, req.LrcPath, safeName+".lrc"); err != nil {
@@ -63,6 +120,329 @@ func handleExportZip(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// exportFormatExt returns the file extension for a format string accepted by
+// ExportZipRequest.Format, defaulting to ".mp3" for "" or "mp3".
+func exportFormatExt(format string) string {
+	switch format {
+	case "flac":
+		return ".flac"
+	case "opus":
+		return ".opus"
+	case "m4a-aac", "m4a-alac":
+		return ".m4a"
+	default:
+		return ".mp3"
+	}
+}
+
+// transcodeAudio converts srcPath (an MP3) to the requested format via
+// ffmpeg, writing the result into cacheDir and returning its path. format ==
+// "" or "mp3" is a no-op that returns srcPath unchanged. When onProgress is
+// non-nil, ffmpeg's "-progress pipe:1" key=value stream is parsed against
+// the source duration (probed via ffmpeg -i) to report percent complete, so
+// callers can relay encoder progress instead of blocking silently.
+func transcodeAudio(srcPath, format string, bitrateKbps, flacCompressionLevel int, cacheDir string, onProgress func(percent float64)) (string, error) {
+	if format == "" || format == "mp3" {
+		return srcPath, nil
+	}
+
+	outPath := filepath.Join(cacheDir, fmt.Sprintf("export_%s%s", randHex(6), exportFormatExt(format)))
+	args := []string{"-y", "-i", srcPath}
+
+	switch format {
+	case "flac":
+		level := flacCompressionLevel
+		if level <= 0 {
+			level = 5
+		}
+		args = append(args, "-c:a", "flac", "-compression_level", strconv.Itoa(level))
+	case "opus":
+		kbps := bitrateKbps
+		if kbps <= 0 {
+			kbps = 128
+		}
+		args = append(args, "-c:a", "libopus", "-b:a", fmt.Sprintf("%dk", kbps))
+	case "m4a-aac":
+		kbps := bitrateKbps
+		if kbps <= 0 {
+			kbps = 192
+		}
+		args = append(args, "-c:a", "aac", "-b:a", fmt.Sprintf("%dk", kbps))
+	case "m4a-alac":
+		args = append(args, "-c:a", "alac")
+	default:
+		return "", fmt.Errorf("export: unsupported format %q", format)
+	}
+
+	var stderr bytes.Buffer
+	if onProgress == nil {
+		args = append(args, outPath)
+		cmd := exec.Command(ffmpegPath, args...)
+		hideWindow(cmd)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("ffmpeg transcode: %w\n%s", err, stderr.String())
+		}
+		return outPath, nil
+	}
+
+	duration, _ := probeDurationSeconds(srcPath)
+	args = append(args, "-progress", "pipe:1", "-nostats", outPath)
+
+	cmd := exec.Command(ffmpegPath, args...)
+	hideWindow(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg transcode: pipe: %w", err)
+	}
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("ffmpeg transcode: start: %w (%s)", err, stderr.String())
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ms, ok := strings.CutPrefix(line, "out_time_ms="); ok && duration > 0 {
+			if us, err := strconv.ParseInt(ms, 10, 64); err == nil {
+				percent := float64(us) / 1e6 / duration * 100
+				if percent > 100 {
+					percent = 100
+				}
+				onProgress(percent)
+			}
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("ffmpeg transcode: %w\n%s", err, stderr.String())
+	}
+	onProgress(100)
+	return outPath, nil
+}
+
+var ffmpegDurationRe = regexp.MustCompile(`Duration: (\d+):(\d+):(\d+\.\d+)`)
+
+// probeDurationSeconds runs `ffmpeg -i path` and parses the "Duration:
+// HH:MM:SS.ss" line ffmpeg always prints to stderr even without an output
+// file (and exits non-zero for, which we ignore).
+func probeDurationSeconds(path string) (float64, error) {
+	cmd := exec.Command(ffmpegPath, "-i", path)
+	hideWindow(cmd)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.Run()
+
+	m := ffmpegDurationRe.FindStringSubmatch(stderr.String())
+	if m == nil {
+		return 0, fmt.Errorf("duration not found in ffmpeg output")
+	}
+	h, _ := strconv.Atoi(m[1])
+	mnt, _ := strconv.Atoi(m[2])
+	s, _ := strconv.ParseFloat(m[3], 64)
+	return float64(h)*3600 + float64(mnt)*60 + s, nil
+}
+
+// ExportEvent is one Server-Sent Event emitted by handleExportZipStream.
+type ExportEvent struct {
+	Stage   string  `json:"stage"` // "transcoding" | "zipping" | "done" | "error"
+	Percent float64 `json:"percent,omitempty"`
+	Path    string  `json:"path,omitempty"` // zip path, on "done" — fetch via /files/serve
+	Error   string  `json:"error,omitempty"`
+}
+
+// handleExportZipStream handles GET /export/zip/stream, running the same
+// transcode+zip pipeline as handleExportZip but emitting SSE progress events
+// as the encode runs instead of blocking silently on a slow FLAC/Opus pass.
+// The final zip is written into cacheDir and its path handed back in the
+// "done" event for the frontend to fetch via /files/serve.
+func handleExportZipStream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	mp3Path := q.Get("mp3_path")
+	lrcPath := q.Get("lrc_path")
+	if mp3Path == "" || lrcPath == "" {
+		http.Error(w, "mp3_path and lrc_path required", 400)
+		return
+	}
+	format := q.Get("format")
+	bitrateKbps, _ := strconv.Atoi(q.Get("bitrate_kbps"))
+	flacLevel, _ := strconv.Atoi(q.Get("flac_compression_level"))
+	mixName := q.Get("mix_name")
+	if mixName == "" {
+		mixName = "AutoMix"
+	}
+	safeName := filepath.Base(mixName)
+	if ext := filepath.Ext(safeName); ext != "" {
+		safeName = safeName[:len(safeName)-len(ext)]
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	emit := func(ev ExportEvent) {
+		data, _ := json.Marshal(ev)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Stage, data)
+		flusher.Flush()
+	}
+
+	audioPath := mp3Path
+	if format != "" && format != "mp3" {
+		emit(ExportEvent{Stage: "transcoding", Percent: 0})
+		transcoded, err := transcodeAudio(mp3Path, format, bitrateKbps, flacLevel, cacheDir, func(percent float64) {
+			emit(ExportEvent{Stage: "transcoding", Percent: percent})
+		})
+		if err != nil {
+			emit(ExportEvent{Stage: "error", Error: err.Error()})
+			return
+		}
+		defer os.Remove(transcoded)
+		audioPath = transcoded
+	}
+
+	emit(ExportEvent{Stage: "zipping", Percent: 0})
+	zipPath := filepath.Join(cacheDir, fmt.Sprintf("export_%s.zip", randHex(6)))
+	zf, err := os.Create(zipPath)
+	if err != nil {
+		emit(ExportEvent{Stage: "error", Error: err.Error()})
+		return
+	}
+	zw := zip.NewWriter(zf)
+	if err := addFileToZip(zw, audioPath, safeName+exportFormatExt(format)); err != nil {
+		zw.Close()
+		zf.Close()
+		emit(ExportEvent{Stage: "error", Error: err.Error()})
+		return
+	}
+	if err := addFileToZip(zw, lrcPath, safeName+".lrc"); err != nil {
+		zw.Close()
+		zf.Close()
+		emit(ExportEvent{Stage: "error", Error: err.Error()})
+		return
+	}
+	zw.Close()
+	zf.Close()
+
+	emit(ExportEvent{Stage: "done", Percent: 100, Path: zipPath})
+}
+
+// ExportFormatsResponse reports which transcode targets the local ffmpeg
+// build actually supports, probed once at startup (see probeExportFormats).
+type ExportFormatsResponse struct {
+	Formats map[string]bool `json:"formats"`
+}
+
+var exportFormatEncoders = map[string]string{
+	"mp3":      "libmp3lame",
+	"flac":     "flac",
+	"opus":     "libopus",
+	"m4a-aac":  "aac",
+	"m4a-alac": "alac",
+}
+
+var supportedExportFormats map[string]bool
+
+// probeExportFormats runs `ffmpeg -encoders` once (mirroring how initFFmpeg
+// resolves the ffmpeg path) and records which of exportFormatEncoders are
+// actually present in this ffmpeg build, for handleGetExportFormats to
+// report. handleExportZip does not consult this map before transcoding.
+func probeExportFormats() {
+	supportedExportFormats = make(map[string]bool, len(exportFormatEncoders))
+	cmd := exec.Command(ffmpegPath, "-hide_banner", "-encoders")
+	hideWindow(cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("Warning: failed to probe ffmpeg encoders: %v", err)
+		return
+	}
+	listing := string(out)
+	for format, encoder := range exportFormatEncoders {
+		supportedExportFormats[format] = strings.Contains(listing, encoder)
+	}
+}
+
+// handleGetExportFormats handles GET /api/export/formats.
+func handleGetExportFormats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExportFormatsResponse{Formats: supportedExportFormats})
+}
+
+// handleExportEmbedded writes a single ID3v2.4-tagged MP3 combining the
+// rendered audio at audioPath with lyrics (USLT+SYLT), cover art (APIC),
+// mix chapters (CHAP/CTOC), and per-track BPM/key/gain (TXXX) — the "embed"
+// alternative to zipping the mp3+lrc pair side by side.
+func handleExportEmbedded(w http.ResponseWriter, req ExportZipRequest, audioPath, safeName string) error {
+	frames := buildEmbedFrames(req)
+
+	audioData, err := os.ReadFile(audioPath)
+	if err != nil {
+		return fmt.Errorf("read audio: %w", err)
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "audio/mpeg")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+safeName+`.mp3"`)
+	w.Write(writeID3v2Tag(frames))
+	w.Write(audioData)
+	return nil
+}
+
+// buildEmbedFrames builds the ID3v2.4 frame set (lyrics, cover, chapters,
+// TXXX) described by req. Factored out of handleExportEmbedded so
+// handleCloudExport can build the same tagged-MP3 bytes without going
+// through an http.ResponseWriter.
+func buildEmbedFrames(req ExportZipRequest) []id3Frame {
+	var frames []id3Frame
+
+	if lrcData, err := os.ReadFile(req.LrcPath); err == nil {
+		lyrics := string(lrcData)
+		frames = append(frames, id3Frame{ID: "USLT", Body: buildUSLTFrame("eng", lyrics)})
+		frames = append(frames, id3Frame{ID: "SYLT", Body: buildSYLTFrame("eng", lyrics)})
+	} else {
+		log.Printf("embed: no lyrics embedded, failed to read %s: %v", req.LrcPath, err)
+	}
+
+	if req.CoverImagePath != "" {
+		if imgData, err := os.ReadFile(req.CoverImagePath); err == nil {
+			mimeType := "image/jpeg"
+			if ext := strings.ToLower(filepath.Ext(req.CoverImagePath)); ext == ".png" {
+				mimeType = "image/png"
+			}
+			frames = append(frames, id3Frame{ID: "APIC", Body: buildAPICFrame(mimeType, 3, imgData)})
+		} else {
+			log.Printf("embed: no cover embedded, failed to read %s: %v", req.CoverImagePath, err)
+		}
+	}
+
+	if len(req.Chapters) > 0 {
+		childIDs := make([]string, len(req.Chapters))
+		for i, ch := range req.Chapters {
+			elementID := fmt.Sprintf("chp%d", i)
+			childIDs[i] = elementID
+			startMs := uint32(ch.StartTime * 1000)
+			endMs := uint32(ch.EndTime * 1000)
+			frames = append(frames, id3Frame{ID: "CHAP", Body: buildCHAPFrame(elementID, startMs, endMs, ch.Title)})
+		}
+		frames = append([]id3Frame{{ID: "CTOC", Body: buildCTOCFrame("toc", childIDs)}}, frames...)
+	}
+
+	for _, tt := range req.TrackTags {
+		prefix := tt.Title
+		if prefix == "" {
+			prefix = "track"
+		}
+		frames = append(frames, id3Frame{ID: "TXXX", Body: buildTXXXFrame(prefix+" BPM", fmt.Sprintf("%.1f", tt.BPM))})
+		frames = append(frames, id3Frame{ID: "TXXX", Body: buildTXXXFrame(prefix+" Key", tt.Key)})
+		frames = append(frames, id3Frame{ID: "TXXX", Body: buildTXXXFrame(prefix+" Gain (dB)", fmt.Sprintf("%.2f", tt.GainDB))})
+	}
+
+	return frames
+}
+
 func addFileToZip(zw *zip.Writer, filePath, zipFilePath string) error {
 	f, err := os.Open(filePath)
 	if err != nil {
@@ -98,6 +478,10 @@ func handleCacheClear(w http.ResponseWriter, r *http.Request) {
 	// Also clean up any _preview.mp3 and _analysis.json files in cache root if they got placed there
 	clearPatternMatch(cacheDir, "*_preview.mp3")
 	clearPatternMatch(cacheDir, "*_analysis.json")
+	// Sweep, not wipe: the norm/chunk caches are meant to survive a render
+	// session's worth of "nudge a transition, re-render" iteration, so only
+	// entries past renderCacheTTL are reclaimed here.
+	sweepRenderCacheTTL(cacheDir)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
@@ -135,8 +519,96 @@ func isChildPath(parent, child string) bool {
 	return !strings.HasPrefix(rel, "..")
 }
 
-// handleServeFile serves a local file as a binary stream for downloading.
-// This prevents Tauri from navigating when setting asset:// URLs on <a> tags.
+// minRangeChunkBytes is the smallest byte range handleServeFile will ever
+// actually return, even if the client asked for less: a seek inside a
+// 90-minute MP3 that only pulls a few KB forces another round trip (and,
+// worse, another mid-frame resync) a few hundred ms later. 64KiB is the
+// same minimum-download-size heuristic librespot uses for its own seeks.
+const minRangeChunkBytes = 64 * 1024
+
+// mp3FrameSyncWindow bounds how far findMP3FrameStart scans forward from a
+// requested byte offset for the next MPEG audio frame sync word, large
+// enough to cover the biggest practical MP3 frame (a few KB at the lowest
+// bitrates) without scanning the whole file on every ranged request.
+const mp3FrameSyncWindow = 4096
+
+// findMP3FrameStart snaps offset forward to the next MPEG audio frame
+// header (11 set sync bits: 0xFF followed by a byte whose top 3 bits are
+// also set) so a range response never starts mid-frame — some players
+// resync cleanly on a partial leading frame, but plenty don't.  Returns
+// offset unchanged if no sync word turns up within mp3FrameSyncWindow.
+func findMP3FrameStart(f *os.File, offset, size int64) int64 {
+	if offset <= 0 || offset >= size-1 {
+		return offset
+	}
+	limit := offset + mp3FrameSyncWindow
+	if limit > size-1 {
+		limit = size - 1
+	}
+	buf := make([]byte, limit-offset+1)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return offset
+	}
+	buf = buf[:n]
+	for i := 0; i < len(buf)-1; i++ {
+		if buf[i] == 0xFF && buf[i+1]&0xE0 == 0xE0 {
+			return offset + int64(i)
+		}
+	}
+	return offset
+}
+
+// parseRangeHeader parses a single-range "bytes=start-end" Range header
+// (including the suffix "bytes=-N" and open-ended "bytes=N-" forms). Multi-
+// range requests ("bytes=0-10,20-30") aren't supported — no caller of
+// /files/serve needs more than one range per request — and are rejected by
+// returning ok=false, same as a malformed header.
+func parseRangeHeader(header string, size int64) (start, end int64, ok bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return 0, 0, false
+	}
+	spec := strings.TrimPrefix(header, prefix)
+	if strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	if parts[0] == "" {
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+	s, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || s < 0 || s >= size {
+		return 0, 0, false
+	}
+	if parts[1] == "" {
+		return s, size - 1, true
+	}
+	e, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || e < s {
+		return 0, 0, false
+	}
+	if e > size-1 {
+		e = size - 1
+	}
+	return s, e, true
+}
+
+// handleServeFile serves a local file as a binary stream for downloading,
+// or (given a Range header) as a 206 Partial Content chunk — the Tauri/
+// Python bridge and any web <audio> player both seek inside long rendered
+// mixes this way. This prevents Tauri from navigating when setting
+// asset:// URLs on <a> tags.
 func handleServeFile(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Query().Get("path")
 	if path == "" {
@@ -167,8 +639,70 @@ func handleServeFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "stat error", 500)
 		return
 	}
+	size := info.Size()
+	name := filepath.Base(absPath)
 
+	// A strong-ish validator built from modtime+size, not a content hash —
+	// good enough to tell "did this file change since the client cached
+	// this ETag" without re-reading the whole file on every range request.
+	etag := fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), size)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
 	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filepath.Base(absPath)))
-	http.ServeContent(w, r, filepath.Base(absPath), info.ModTime(), f)
+
+	disposition := "attachment"
+	if r.URL.Query().Get("disposition") == "inline" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%s", disposition, name))
+
+	rangeHeader := r.Header.Get("Range")
+	ifRange := r.Header.Get("If-Range")
+	// If-Range names a validator the client's cached range was served
+	// against; a mismatch means that cache is stale, so fall back to a
+	// full 200 rather than splicing a stale range onto fresh bytes.
+	if rangeHeader == "" || (ifRange != "" && ifRange != etag) {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			io.Copy(w, f)
+		}
+		return
+	}
+
+	start, end, ok := parseRangeHeader(rangeHeader, size)
+	if !ok {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, "invalid range", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	// Snapping start to the next frame sync word only makes sense for
+	// inline playback/seeking, where the client discards whatever partial
+	// frame comes back; an attachment range is a resumable download, and
+	// shifting start forward would leave a permanent gap where the
+	// skipped bytes belong once the client appends this range to what it
+	// already has on disk.
+	if disposition == "inline" && strings.HasSuffix(strings.ToLower(name), ".mp3") {
+		start = findMP3FrameStart(f, start, size)
+	}
+	if end-start+1 < minRangeChunkBytes {
+		end = start + minRangeChunkBytes - 1
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		http.Error(w, "seek error", 500)
+		return
+	}
+
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	w.WriteHeader(http.StatusPartialContent)
+	if r.Method != http.MethodHead {
+		io.CopyN(w, f, end-start+1)
+	}
 }
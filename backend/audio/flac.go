@@ -0,0 +1,84 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+)
+
+// decodeFLAC fully decodes a FLAC stream, downmixing to mono float32.
+func decodeFLAC(r io.Reader) ([]float32, int, error) {
+	stream, err := flac.New(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("audio: flac decode: %w", err)
+	}
+	channels := int(stream.Info.NChannels)
+	scale := float32(int64(1) << (stream.Info.BitsPerSample - 1))
+
+	var interleaved []float32
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("audio: flac frame: %w", err)
+		}
+		numSamples := len(frame.Subframes[0].Samples)
+		for i := 0; i < numSamples; i++ {
+			for ch := 0; ch < channels; ch++ {
+				interleaved = append(interleaved, float32(frame.Subframes[ch].Samples[i])/scale)
+			}
+		}
+	}
+	return downmix(interleaved, channels), int(stream.Info.SampleRate), nil
+}
+
+// flacStreamDecoder decodes one flac.Frame at a time and doles out
+// blockSize-sample chunks from a small carry-over buffer, since FLAC frame
+// sizes don't line up with arbitrary block sizes.
+type flacStreamDecoder struct {
+	stream   *flac.Stream
+	channels int
+	scale    float32
+	carry    []float32
+}
+
+func newFLACStreamDecoder(r io.Reader) (streamDecoder, int, error) {
+	stream, err := flac.New(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("audio: flac decode: %w", err)
+	}
+	d := &flacStreamDecoder{
+		stream:   stream,
+		channels: int(stream.Info.NChannels),
+		scale:    float32(int64(1) << (stream.Info.BitsPerSample - 1)),
+	}
+	return d, int(stream.Info.SampleRate), nil
+}
+
+func (d *flacStreamDecoder) readBlock(n int) ([]float32, error) {
+	for len(d.carry) < n {
+		frame, err := d.stream.ParseNext()
+		if err != nil {
+			if len(d.carry) > 0 {
+				block := d.carry
+				d.carry = nil
+				return block, nil
+			}
+			return nil, err
+		}
+		numSamples := len(frame.Subframes[0].Samples)
+		interleaved := make([]float32, 0, numSamples*d.channels)
+		for i := 0; i < numSamples; i++ {
+			for ch := 0; ch < d.channels; ch++ {
+				interleaved = append(interleaved, float32(frame.Subframes[ch].Samples[i])/d.scale)
+			}
+		}
+		d.carry = append(d.carry, downmix(interleaved, d.channels)...)
+	}
+	block := d.carry[:n]
+	d.carry = d.carry[n:]
+	return block, nil
+}
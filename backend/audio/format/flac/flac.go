@@ -0,0 +1,137 @@
+// Package flac registers a native FLAC decoder with backend/audio/format
+// for RenderFinalMix's native mix path.
+package flac
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+
+	"github.com/vividhyeok/djbot/backend/audio"
+	"github.com/vividhyeok/djbot/backend/audio/format"
+)
+
+func init() {
+	format.Register("flac", Open)
+}
+
+func toStereo(interleaved []float32, channels int) []float32 {
+	if channels == 2 {
+		return interleaved
+	}
+	n := len(interleaved) / channels
+	out := make([]float32, n*2)
+	for i := 0; i < n; i++ {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += interleaved[i*channels+c]
+		}
+		mono := sum / float32(channels)
+		out[i*2] = mono
+		out[i*2+1] = mono
+	}
+	return out
+}
+
+// Open decodes r as a FLAC stream and streams it in format.BlockFrames-frame
+// stereo blocks at format.TargetSampleRate, decoding one flac.Frame at a
+// time (frame sizes don't line up with BlockFrames, so leftovers carry over
+// between frames same as backend/audio's flacStreamDecoder). Non-44.1kHz
+// sources are decoded fully up front and resampled as one buffer, same
+// reasoning as format/wav.Open and format/mp3.Open.
+func Open(r io.ReadSeeker) (*format.Source, error) {
+	stream, err := flac.New(r)
+	if err != nil {
+		return nil, fmt.Errorf("format/flac: decode: %w", err)
+	}
+	channels := int(stream.Info.NChannels)
+	scale := float32(int64(1) << (stream.Info.BitsPerSample - 1))
+	sr := int(stream.Info.SampleRate)
+
+	src := &format.Source{SampleRate: format.TargetSampleRate, Channels: format.TargetChannels}
+
+	if sr != format.TargetSampleRate {
+		var interleaved []float32
+		for {
+			frame, err := stream.ParseNext()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("format/flac: frame: %w", err)
+			}
+			numSamples := len(frame.Subframes[0].Samples)
+			for i := 0; i < numSamples; i++ {
+				for ch := 0; ch < channels; ch++ {
+					interleaved = append(interleaved, float32(frame.Subframes[ch].Samples[i])/scale)
+				}
+			}
+		}
+		stereo := toStereo(interleaved, channels)
+		left := make([]float32, len(stereo)/2)
+		right := make([]float32, len(stereo)/2)
+		for i := range left {
+			left[i] = stereo[i*2]
+			right[i] = stereo[i*2+1]
+		}
+		left = audio.Resample(left, sr, format.TargetSampleRate)
+		right = audio.Resample(right, sr, format.TargetSampleRate)
+		n := len(left)
+		if len(right) < n {
+			n = len(right)
+		}
+		resampled := make([]float32, n*2)
+		for i := 0; i < n; i++ {
+			resampled[i*2] = left[i]
+			resampled[i*2+1] = right[i]
+		}
+
+		blocks := make(chan []float32, 4)
+		src.Blocks = blocks
+		go func() {
+			defer close(blocks)
+			for start := 0; start < len(resampled); start += format.BlockFrames * 2 {
+				end := start + format.BlockFrames*2
+				if end > len(resampled) {
+					end = len(resampled)
+				}
+				blocks <- resampled[start:end]
+			}
+		}()
+		return src, nil
+	}
+
+	blocks := make(chan []float32, 4)
+	src.Blocks = blocks
+	go func() {
+		defer close(blocks)
+		var carry []float32
+		for {
+			for len(carry) < format.BlockFrames*2 {
+				frame, err := stream.ParseNext()
+				if err != nil {
+					if len(carry) > 0 {
+						blocks <- carry
+					}
+					if err != io.EOF {
+						src.Fail(fmt.Errorf("format/flac: frame: %w", err))
+					}
+					return
+				}
+				numSamples := len(frame.Subframes[0].Samples)
+				raw := make([]float32, 0, numSamples*channels)
+				for i := 0; i < numSamples; i++ {
+					for ch := 0; ch < channels; ch++ {
+						raw = append(raw, float32(frame.Subframes[ch].Samples[i])/scale)
+					}
+				}
+				carry = append(carry, toStereo(raw, channels)...)
+			}
+			block := carry[:format.BlockFrames*2]
+			carry = carry[format.BlockFrames*2:]
+			blocks <- block
+		}
+	}()
+	return src, nil
+}
@@ -0,0 +1,119 @@
+// Package format streams decoded, stereo-preserving PCM for the renderer's
+// native (non-ffmpeg) mix path. It is a sibling to backend/audio rather
+// than a replacement for it: backend/audio downmixes to mono for
+// BPM/key/fingerprint analysis, while this package keeps both channels (and
+// resamples to a common rate) so RenderFinalMix can mix tracks straight
+// into its canvas without shelling out to ffmpeg for every chunk.
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// BlockFrames is the stereo frame count per element on Source.Blocks —
+	// roughly 93ms at 44.1kHz, the same order of magnitude as the chunk
+	// size backend/audio's streaming decoders use for mono analysis.
+	BlockFrames = 4096
+
+	// TargetSampleRate is the rate every Source is resampled to before its
+	// blocks reach the caller, so RenderFinalMix's canvas overlay never has
+	// to special-case a track's native rate.
+	TargetSampleRate = 44100
+
+	// TargetChannels is always 2: mono sources are duplicated to both
+	// channels rather than exposed as 1-channel blocks, so callers never
+	// need a channel-count branch.
+	TargetChannels = 2
+)
+
+// Source streams decoded interleaved float32 stereo PCM at TargetSampleRate
+// in fixed-size blocks over Blocks, mirroring the events-over-a-channel
+// pattern backend/audio.StreamBlock already uses for mono analysis decode.
+// Blocks is closed once the decoder reaches EOF or hits a fatal error; call
+// Err after it closes to find out which.
+type Source struct {
+	SampleRate int // always TargetSampleRate
+	Channels   int // always TargetChannels
+	Blocks     <-chan []float32
+
+	err error
+}
+
+// Fail records a fatal decode error. Sub-package Opens call this from their
+// decode goroutine right before closing the channel they own; it is
+// exported so those goroutines (in a different package) can set it.
+func (s *Source) Fail(err error) {
+	s.err = err
+}
+
+// Err returns the error (if any) that ended the stream early. Only
+// meaningful after Blocks has been drained/closed.
+func (s *Source) Err() error {
+	return s.err
+}
+
+// Opener decodes one container/codec into a Source, reading from the start
+// of r. Each sub-package (wav, mp3, flac, opus) implements this against its
+// own library and registers itself via Register in an init().
+type Opener func(r io.ReadSeeker) (*Source, error)
+
+var openers = map[string]Opener{}
+
+// Register wires ext (no leading dot, e.g. "mp3") to open. Kept in its own
+// package-level map — rather than importing every sub-package directly
+// from here, which would make backend/audio/format depend on all of
+// wav/mp3/flac/opus and them depend back on it — the same
+// init()-overrides-a-dispatch-map idiom backend/tags uses for its optional
+// cgo taglib backend.
+func Register(ext string, open Opener) {
+	openers[strings.ToLower(ext)] = open
+}
+
+// Open dispatches to the sub-package registered for ext. Callers (see
+// RenderFinalMix) treat a non-nil error here as "no native decoder for this
+// container, fall back to ffmpeg" rather than a hard failure — notably,
+// backend/audio/format/opus never registers, so .opus/.ogg sources always
+// take that fallback today.
+func Open(r io.ReadSeeker, ext string) (*Source, error) {
+	open, ok := openers[strings.ToLower(ext)]
+	if !ok {
+		return nil, fmt.Errorf("format: no native decoder registered for %q", ext)
+	}
+	return open(r)
+}
+
+// FromSlice wraps an already-decoded interleaved stereo buffer as a Source,
+// for callers that decode once up front (e.g. RenderFinalMix's per-track
+// normalization pre-pass) and then want to run the result through the same
+// Atrim/Gain/FadeIn/FadeOut transform chain as a freshly Open'd file.
+func FromSlice(samples []float32, sr int) *Source {
+	blocks := make(chan []float32, 4)
+	src := &Source{SampleRate: sr, Channels: TargetChannels, Blocks: blocks}
+	go func() {
+		defer close(blocks)
+		for start := 0; start < len(samples); start += BlockFrames * 2 {
+			end := start + BlockFrames*2
+			if end > len(samples) {
+				end = len(samples)
+			}
+			blocks <- samples[start:end]
+		}
+	}()
+	return src
+}
+
+// Drain reads every block off src.Blocks into one interleaved stereo slice.
+// It materializes the whole stream in memory, which is fine for the
+// per-track buffers RenderFinalMix already holds (it previously held the
+// same data as an on-disk PCM/WAV file); true block-at-a-time consumption
+// is for future callers that don't need the whole track at once.
+func Drain(src *Source) ([]float32, error) {
+	var out []float32
+	for block := range src.Blocks {
+		out = append(out, block...)
+	}
+	return out, src.Err()
+}
@@ -0,0 +1,104 @@
+// Package mp3 registers a native MP3 decoder with backend/audio/format for
+// RenderFinalMix's native mix path.
+package mp3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+
+	"github.com/vividhyeok/djbot/backend/audio"
+	"github.com/vividhyeok/djbot/backend/audio/format"
+)
+
+func init() {
+	format.Register("mp3", Open)
+}
+
+func pcm16ToFloat32(raw []byte) []float32 {
+	n := len(raw) / 2
+	out := make([]float32, n)
+	for i := 0; i < n; i++ {
+		v := int16(binary.LittleEndian.Uint16(raw[i*2 : i*2+2]))
+		out[i] = float32(v) / 32768.0
+	}
+	return out
+}
+
+// Open decodes r as an MP3 stream and streams it in format.BlockFrames-frame
+// stereo blocks at format.TargetSampleRate. go-mp3 always outputs 16-bit LE
+// stereo PCM regardless of the source channel count, same as
+// backend/audio's decodeMP3, so there's no mono/stereo branch here.
+// Non-44.1kHz sources are decoded fully up front and resampled as one
+// buffer, for the same reason format/wav.Open does: a streaming
+// block-by-block resample would ring at every block boundary.
+func Open(r io.ReadSeeker) (*format.Source, error) {
+	dec, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("format/mp3: decode: %w", err)
+	}
+
+	src := &format.Source{SampleRate: format.TargetSampleRate, Channels: format.TargetChannels}
+
+	if dec.SampleRate() != format.TargetSampleRate {
+		raw, err := io.ReadAll(dec)
+		if err != nil {
+			return nil, fmt.Errorf("format/mp3: read: %w", err)
+		}
+		interleaved := pcm16ToFloat32(raw)
+		left := make([]float32, len(interleaved)/2)
+		right := make([]float32, len(interleaved)/2)
+		for i := range left {
+			left[i] = interleaved[i*2]
+			right[i] = interleaved[i*2+1]
+		}
+		left = audio.Resample(left, dec.SampleRate(), format.TargetSampleRate)
+		right = audio.Resample(right, dec.SampleRate(), format.TargetSampleRate)
+		n := len(left)
+		if len(right) < n {
+			n = len(right)
+		}
+		resampled := make([]float32, n*2)
+		for i := 0; i < n; i++ {
+			resampled[i*2] = left[i]
+			resampled[i*2+1] = right[i]
+		}
+
+		blocks := make(chan []float32, 4)
+		src.Blocks = blocks
+		go func() {
+			defer close(blocks)
+			for start := 0; start < len(resampled); start += format.BlockFrames * 2 {
+				end := start + format.BlockFrames*2
+				if end > len(resampled) {
+					end = len(resampled)
+				}
+				blocks <- resampled[start:end]
+			}
+		}()
+		return src, nil
+	}
+
+	blocks := make(chan []float32, 4)
+	src.Blocks = blocks
+	go func() {
+		defer close(blocks)
+		raw := make([]byte, format.BlockFrames*2*2)
+		for {
+			n, err := io.ReadFull(dec, raw)
+			if n > 0 {
+				chunk := raw[:n-n%4]
+				blocks <- pcm16ToFloat32(chunk)
+			}
+			if err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					src.Fail(fmt.Errorf("format/mp3: read: %w", err))
+				}
+				return
+			}
+		}
+	}()
+	return src, nil
+}
@@ -0,0 +1,22 @@
+// Package opus is a placeholder: this repo doesn't vendor a native Opus
+// decoder (unlike mp3/flac/wav, which wrap go-mp3/mewkiz/flac/a hand-rolled
+// RIFF reader), so Open always errors.
+package opus
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/vividhyeok/djbot/backend/audio/format"
+)
+
+// Open never succeeds. It deliberately does NOT call format.Register in an
+// init(), so format.Open returns the same "no decoder registered" error for
+// .opus/.ogg sources as it would for any other unhandled extension, and
+// RenderFinalMix's native-decode fast path falls back to its existing
+// ffmpeg pipeline for them. Kept as a real function (not just a comment) so
+// the package still satisfies the mp3/flac/wav/opus sub-package shape if a
+// future contributor wires in a real decoder.
+func Open(r io.ReadSeeker) (*format.Source, error) {
+	return nil, fmt.Errorf("format/opus: no native Opus decoder available")
+}
@@ -0,0 +1,142 @@
+package format
+
+import "math"
+
+// transformSource builds a new Source whose Blocks channel is fed by work,
+// the shared plumbing every transform below needs (own channel, forward
+// src's error once src.Blocks closes).
+func transformSource(src *Source, work func(out chan<- []float32)) *Source {
+	out := &Source{SampleRate: src.SampleRate, Channels: src.Channels}
+	blocks := make(chan []float32, 4)
+	out.Blocks = blocks
+	go func() {
+		defer close(blocks)
+		work(blocks)
+		if err := src.Err(); err != nil {
+			out.Fail(err)
+		}
+	}()
+	return out
+}
+
+// Gain wraps src with a flat gainDB applied to every sample — the Go
+// transform equivalent of the ffmpeg `volume=XdB` stage RenderFinalMix's
+// main loop used to shell out for. RenderFinalMix computes gainDB the same
+// way either path: targetLUFS minus the track's measured LoudnessDB,
+// clamped against its true peak (see the gainDB calculation in
+// RenderFinalMix) — this is that clamped figure applied as a transform
+// rather than a filter string.
+func Gain(src *Source, gainDB float64) *Source {
+	mult := float32(math.Pow(10, gainDB/20))
+	return transformSource(src, func(out chan<- []float32) {
+		for block := range src.Blocks {
+			scaled := make([]float32, len(block))
+			for i, v := range block {
+				scaled[i] = v * mult
+			}
+			out <- scaled
+		}
+	})
+}
+
+// Atrim keeps only the stereo frames between [startSec, endSec), discarding
+// everything before and forwarding nothing once endSec is reached; the rest
+// of src is still read through to EOF (and discarded) before the output
+// closes — the transform equivalent of ffmpeg's `atrim=start=...:end=...`.
+func Atrim(src *Source, startSec, endSec float64) *Source {
+	startFrame := int64(startSec * float64(src.SampleRate))
+	endFrame := int64(endSec * float64(src.SampleRate))
+	return transformSource(src, func(out chan<- []float32) {
+		var frame int64
+		for block := range src.Blocks {
+			n := int64(len(block) / 2)
+			blockStart, blockEnd := frame, frame+n
+			frame = blockEnd
+
+			if blockEnd <= startFrame {
+				continue
+			}
+			if blockStart >= endFrame {
+				// Drain the rest of src without forwarding so its decode
+				// goroutine isn't left blocked on a full channel.
+				for range src.Blocks {
+				}
+				return
+			}
+
+			loFrame := blockStart
+			if loFrame < startFrame {
+				loFrame = startFrame
+			}
+			hiFrame := blockEnd
+			if hiFrame > endFrame {
+				hiFrame = endFrame
+			}
+			lo := int(loFrame-blockStart) * 2
+			hi := int(hiFrame-blockStart) * 2
+			if hi > lo {
+				trimmed := make([]float32, hi-lo)
+				copy(trimmed, block[lo:hi])
+				out <- trimmed
+			}
+		}
+	})
+}
+
+// FadeIn ramps amplitude linearly from 0 to 1 over the first seconds of
+// src — the transform equivalent of ffmpeg's `afade=t=in:d=...`.
+func FadeIn(src *Source, seconds float64) *Source {
+	rampFrames := int64(seconds * float64(src.SampleRate))
+	return transformSource(src, func(out chan<- []float32) {
+		var frame int64
+		for block := range src.Blocks {
+			n := len(block) / 2
+			faded := make([]float32, len(block))
+			for i := 0; i < n; i++ {
+				f := frame + int64(i)
+				gain := float32(1.0)
+				if rampFrames > 0 && f < rampFrames {
+					gain = float32(f) / float32(rampFrames)
+				}
+				faded[i*2] = block[i*2] * gain
+				faded[i*2+1] = block[i*2+1] * gain
+			}
+			frame += int64(n)
+			out <- faded
+		}
+	})
+}
+
+// FadeOut ramps amplitude linearly from 1 to 0 over the last seconds of
+// src — the transform equivalent of ffmpeg's `afade=t=out:st=...:d=...`.
+// Unlike FadeIn, it needs to know how long src runs in advance (a live
+// stream's length isn't known until EOF), so callers pass totalSeconds —
+// in RenderFinalMix this is always the same endSec-startSec already used to
+// build the matching Atrim stage.
+func FadeOut(src *Source, totalSeconds, seconds float64) *Source {
+	totalFrames := int64(totalSeconds * float64(src.SampleRate))
+	rampFrames := int64(seconds * float64(src.SampleRate))
+	fadeStartFrame := totalFrames - rampFrames
+	return transformSource(src, func(out chan<- []float32) {
+		var frame int64
+		for block := range src.Blocks {
+			n := len(block) / 2
+			faded := make([]float32, len(block))
+			for i := 0; i < n; i++ {
+				f := frame + int64(i)
+				gain := float32(1.0)
+				if rampFrames > 0 && f >= fadeStartFrame {
+					remaining := totalFrames - f
+					if remaining < 0 {
+						remaining = 0
+					}
+					gain = float32(remaining) / float32(rampFrames)
+				}
+				faded[i*2] = block[i*2] * gain
+				faded[i*2+1] = block[i*2+1] * gain
+			}
+			frame += int64(n)
+			out <- faded
+		}
+	})
+}
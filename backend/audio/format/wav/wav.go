@@ -0,0 +1,220 @@
+// Package wav registers a native WAV/RIFF decoder with backend/audio/format
+// for RenderFinalMix's native mix path.
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/vividhyeok/djbot/backend/audio"
+	"github.com/vividhyeok/djbot/backend/audio/format"
+)
+
+func init() {
+	format.Register("wav", Open)
+	format.Register("wave", Open)
+}
+
+type wavFormat struct {
+	audioFormat   uint16
+	channels      uint16
+	sampleRate    uint32
+	bitsPerSample uint16
+}
+
+// readHeader walks the RIFF chunk list until it finds "fmt " and "data",
+// returning the format plus a reader positioned at the start of the data
+// chunk — duplicated from backend/audio's unexported readWAVHeader since
+// that one downmixes its callers straight to mono and isn't exported.
+func readHeader(r io.Reader) (wavFormat, io.Reader, int, error) {
+	var fmtHdr wavFormat
+
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return fmtHdr, nil, 0, fmt.Errorf("format/wav: read RIFF header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return fmtHdr, nil, 0, fmt.Errorf("format/wav: not a WAV file")
+	}
+
+	for {
+		var chunkHdr [8]byte
+		if _, err := io.ReadFull(r, chunkHdr[:]); err != nil {
+			return fmtHdr, nil, 0, fmt.Errorf("format/wav: read chunk header: %w", err)
+		}
+		chunkID := string(chunkHdr[0:4])
+		chunkSize := binary.LittleEndian.Uint32(chunkHdr[4:8])
+
+		switch chunkID {
+		case "fmt ":
+			body := make([]byte, chunkSize)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return fmtHdr, nil, 0, fmt.Errorf("format/wav: read fmt chunk: %w", err)
+			}
+			if len(body) < 16 {
+				return fmtHdr, nil, 0, fmt.Errorf("format/wav: fmt chunk too short: %d bytes", len(body))
+			}
+			fmtHdr.audioFormat = binary.LittleEndian.Uint16(body[0:2])
+			fmtHdr.channels = binary.LittleEndian.Uint16(body[2:4])
+			fmtHdr.sampleRate = binary.LittleEndian.Uint32(body[4:8])
+			fmtHdr.bitsPerSample = binary.LittleEndian.Uint16(body[14:16])
+		case "data":
+			return fmtHdr, io.LimitReader(r, int64(chunkSize)), int(chunkSize), nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(chunkSize)+int64(chunkSize&1)); err != nil {
+				return fmtHdr, nil, 0, fmt.Errorf("format/wav: skip chunk %q: %w", chunkID, err)
+			}
+		}
+	}
+}
+
+func bytesToFloat32(raw []byte, f wavFormat) ([]float32, error) {
+	bytesPerSample := int(f.bitsPerSample) / 8
+	if bytesPerSample == 0 {
+		return nil, fmt.Errorf("format/wav: invalid bit depth %d", f.bitsPerSample)
+	}
+	n := len(raw) / bytesPerSample
+	out := make([]float32, n)
+
+	switch {
+	case f.audioFormat == 3 && f.bitsPerSample == 32:
+		for i := 0; i < n; i++ {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4 : i*4+4]))
+		}
+	case f.audioFormat == 1 && f.bitsPerSample == 16:
+		for i := 0; i < n; i++ {
+			v := int16(binary.LittleEndian.Uint16(raw[i*2 : i*2+2]))
+			out[i] = float32(v) / 32768.0
+		}
+	case f.audioFormat == 1 && f.bitsPerSample == 24:
+		for i := 0; i < n; i++ {
+			b := raw[i*3 : i*3+3]
+			v := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16
+			if v&0x800000 != 0 {
+				v |= ^int32(0xFFFFFF)
+			}
+			out[i] = float32(v) / 8388608.0
+		}
+	case f.audioFormat == 1 && f.bitsPerSample == 32:
+		for i := 0; i < n; i++ {
+			v := int32(binary.LittleEndian.Uint32(raw[i*4 : i*4+4]))
+			out[i] = float32(v) / 2147483648.0
+		}
+	default:
+		return nil, fmt.Errorf("format/wav: unsupported WAV format (fmt=%d, bits=%d)", f.audioFormat, f.bitsPerSample)
+	}
+	return out, nil
+}
+
+func toStereo(interleaved []float32, channels int) []float32 {
+	if channels == 2 {
+		return interleaved
+	}
+	n := len(interleaved) / channels
+	out := make([]float32, n*2)
+	for i := 0; i < n; i++ {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += interleaved[i*channels+c]
+		}
+		mono := sum / float32(channels)
+		out[i*2] = mono
+		out[i*2+1] = mono
+	}
+	return out
+}
+
+// Open decodes r as a WAV/RIFF stream and streams it in
+// format.BlockFrames-frame stereo blocks at format.TargetSampleRate. Mono
+// (or any non-stereo count) is duplicated to both channels. Sources already
+// at TargetSampleRate stream block-by-block straight off the data chunk;
+// anything else is decoded up front and resampled as one buffer so
+// audio.Resample's windowed-sinc kernel sees the whole signal instead of
+// per-block fragments, which would ring at every block boundary.
+func Open(r io.ReadSeeker) (*format.Source, error) {
+	hdr, data, dataSize, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	channels := int(hdr.channels)
+	if channels < 1 {
+		return nil, fmt.Errorf("format/wav: invalid channel count %d", channels)
+	}
+
+	src := &format.Source{SampleRate: format.TargetSampleRate, Channels: format.TargetChannels}
+
+	if int(hdr.sampleRate) != format.TargetSampleRate {
+		raw, err := io.ReadAll(io.LimitReader(data, int64(dataSize)))
+		if err != nil {
+			return nil, fmt.Errorf("format/wav: read data: %w", err)
+		}
+		interleaved, err := bytesToFloat32(raw, hdr)
+		if err != nil {
+			return nil, err
+		}
+		stereo := toStereo(interleaved, channels)
+		left := make([]float32, len(stereo)/2)
+		right := make([]float32, len(stereo)/2)
+		for i := range left {
+			left[i] = stereo[i*2]
+			right[i] = stereo[i*2+1]
+		}
+		left = audio.Resample(left, int(hdr.sampleRate), format.TargetSampleRate)
+		right = audio.Resample(right, int(hdr.sampleRate), format.TargetSampleRate)
+		n := len(left)
+		if len(right) < n {
+			n = len(right)
+		}
+		resampled := make([]float32, n*2)
+		for i := 0; i < n; i++ {
+			resampled[i*2] = left[i]
+			resampled[i*2+1] = right[i]
+		}
+
+		blocks := make(chan []float32, 4)
+		src.Blocks = blocks
+		go func() {
+			defer close(blocks)
+			for start := 0; start < len(resampled); start += format.BlockFrames * 2 {
+				end := start + format.BlockFrames*2
+				if end > len(resampled) {
+					end = len(resampled)
+				}
+				blocks <- resampled[start:end]
+			}
+		}()
+		return src, nil
+	}
+
+	bytesPerSample := int(hdr.bitsPerSample) / 8
+	if bytesPerSample == 0 {
+		return nil, fmt.Errorf("format/wav: invalid bit depth %d", hdr.bitsPerSample)
+	}
+	blocks := make(chan []float32, 4)
+	src.Blocks = blocks
+	go func() {
+		defer close(blocks)
+		raw := make([]byte, format.BlockFrames*channels*bytesPerSample)
+		for {
+			n, err := io.ReadFull(data, raw)
+			if n > 0 {
+				chunk := raw[:n-n%(channels*bytesPerSample)]
+				interleaved, convErr := bytesToFloat32(chunk, hdr)
+				if convErr != nil {
+					src.Fail(fmt.Errorf("format/wav: %w", convErr))
+					return
+				}
+				blocks <- toStereo(interleaved, channels)
+			}
+			if err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					src.Fail(fmt.Errorf("format/wav: read: %w", err))
+				}
+				return
+			}
+		}
+	}()
+	return src, nil
+}
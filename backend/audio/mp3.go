@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// decodeMP3 fully decodes an MP3 stream. go-mp3 always outputs 16-bit LE
+// stereo PCM regardless of the source channel count, so we downmix from 2
+// channels unconditionally.
+func decodeMP3(r io.Reader) ([]float32, int, error) {
+	dec, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("audio: mp3 decode: %w", err)
+	}
+	raw, err := io.ReadAll(dec)
+	if err != nil {
+		return nil, 0, fmt.Errorf("audio: mp3 read: %w", err)
+	}
+	interleaved := pcm16ToFloat32(raw)
+	return downmix(interleaved, 2), dec.SampleRate(), nil
+}
+
+type mp3StreamDecoder struct {
+	dec *mp3.Decoder
+}
+
+func newMP3StreamDecoder(r io.Reader) (streamDecoder, int, error) {
+	dec, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("audio: mp3 decode: %w", err)
+	}
+	return &mp3StreamDecoder{dec: dec}, dec.SampleRate(), nil
+}
+
+func (d *mp3StreamDecoder) readBlock(n int) ([]float32, error) {
+	// 2 channels, 16-bit samples per frame.
+	raw := make([]byte, n*2*2)
+	read, err := io.ReadFull(d.dec, raw)
+	if read == 0 {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, err
+	}
+	raw = raw[:read-read%4]
+	block := downmix(pcm16ToFloat32(raw), 2)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return block, err
+}
+
+func pcm16ToFloat32(raw []byte) []float32 {
+	n := len(raw) / 2
+	out := make([]float32, n)
+	for i := 0; i < n; i++ {
+		v := int16(binary.LittleEndian.Uint16(raw[i*2 : i*2+2]))
+		out[i] = float32(v) / 32768.0
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package audio
+
+import "math"
+
+// resampleTaps is the half-width (in source samples either side of the
+// target position) of the windowed-sinc kernel used by Resample.
+const resampleTaps = 16
+
+// Resample converts samples from srcRate to dstRate using a windowed-sinc
+// polyphase FIR. This is the shared resampler for anything in this codebase
+// that needs a specific analysis rate (the fingerprinter's fixed 16kHz
+// grid, in particular) — it aliases far less than naive linear
+// interpolation when downsampling.
+func Resample(samples []float32, srcRate, dstRate int) []float32 {
+	if srcRate == dstRate || len(samples) == 0 {
+		return samples
+	}
+	ratio := float64(dstRate) / float64(srcRate)
+	cutoff := ratio
+	if cutoff > 1 {
+		cutoff = 1
+	}
+	outLen := int(float64(len(samples)) * ratio)
+	out := make([]float32, outLen)
+
+	for i := range out {
+		srcPos := float64(i) / ratio
+		center := int(math.Floor(srcPos))
+		var sum, norm float64
+		for k := -resampleTaps; k <= resampleTaps; k++ {
+			idx := center + k
+			if idx < 0 || idx >= len(samples) {
+				continue
+			}
+			x := srcPos - float64(idx)
+			w := windowedSinc(x, cutoff)
+			sum += float64(samples[idx]) * w
+			norm += w
+		}
+		if norm > 1e-9 {
+			out[i] = float32(sum / norm)
+		}
+	}
+	return out
+}
+
+// windowedSinc evaluates a Hann-windowed sinc kernel scaled to the given
+// cutoff (cutoff < 1 widens the main lobe to low-pass filter before
+// decimating, preventing aliasing).
+func windowedSinc(x, cutoff float64) float64 {
+	xc := x * cutoff
+	var s float64
+	if math.Abs(xc) < 1e-9 {
+		s = 1.0
+	} else {
+		s = math.Sin(math.Pi*xc) / (math.Pi * xc)
+	}
+	win := 0.5 * (1 + math.Cos(math.Pi*x/float64(resampleTaps)))
+	return s * cutoff * win
+}
@@ -0,0 +1,153 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+type wavFormat struct {
+	audioFormat   uint16 // 1 = PCM, 3 = IEEE float
+	channels      uint16
+	sampleRate    uint32
+	bitsPerSample uint16
+}
+
+// readWAVHeader walks the RIFF chunk list until it finds "fmt " and "data",
+// returning the format plus a reader positioned at the start of the data
+// chunk (bounded to dataSize bytes).
+func readWAVHeader(r io.Reader) (wavFormat, io.Reader, int, error) {
+	var fmtHdr wavFormat
+
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return fmtHdr, nil, 0, fmt.Errorf("audio: read RIFF header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return fmtHdr, nil, 0, fmt.Errorf("audio: not a WAV file")
+	}
+
+	for {
+		var chunkHdr [8]byte
+		if _, err := io.ReadFull(r, chunkHdr[:]); err != nil {
+			return fmtHdr, nil, 0, fmt.Errorf("audio: read chunk header: %w", err)
+		}
+		chunkID := string(chunkHdr[0:4])
+		chunkSize := binary.LittleEndian.Uint32(chunkHdr[4:8])
+
+		switch chunkID {
+		case "fmt ":
+			body := make([]byte, chunkSize)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return fmtHdr, nil, 0, fmt.Errorf("audio: read fmt chunk: %w", err)
+			}
+			if len(body) < 16 {
+				return fmtHdr, nil, 0, fmt.Errorf("audio: fmt chunk too short: %d bytes", len(body))
+			}
+			fmtHdr.audioFormat = binary.LittleEndian.Uint16(body[0:2])
+			fmtHdr.channels = binary.LittleEndian.Uint16(body[2:4])
+			fmtHdr.sampleRate = binary.LittleEndian.Uint32(body[4:8])
+			fmtHdr.bitsPerSample = binary.LittleEndian.Uint16(body[14:16])
+		case "data":
+			return fmtHdr, io.LimitReader(r, int64(chunkSize)), int(chunkSize), nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(chunkSize)+int64(chunkSize&1)); err != nil {
+				return fmtHdr, nil, 0, fmt.Errorf("audio: skip chunk %q: %w", chunkID, err)
+			}
+		}
+	}
+}
+
+func decodeWAV(r io.Reader) ([]float32, int, error) {
+	format, data, dataSize, err := readWAVHeader(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	raw, err := io.ReadAll(io.LimitReader(data, int64(dataSize)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("audio: read WAV data: %w", err)
+	}
+	interleaved, err := wavBytesToFloat32(raw, format)
+	if err != nil {
+		return nil, 0, err
+	}
+	return downmix(interleaved, int(format.channels)), int(format.sampleRate), nil
+}
+
+func wavBytesToFloat32(raw []byte, format wavFormat) ([]float32, error) {
+	bytesPerSample := int(format.bitsPerSample) / 8
+	if bytesPerSample == 0 {
+		return nil, fmt.Errorf("audio: invalid WAV bit depth %d", format.bitsPerSample)
+	}
+	n := len(raw) / bytesPerSample
+	out := make([]float32, n)
+
+	switch {
+	case format.audioFormat == 3 && format.bitsPerSample == 32:
+		for i := 0; i < n; i++ {
+			bits := binary.LittleEndian.Uint32(raw[i*4 : i*4+4])
+			out[i] = math.Float32frombits(bits)
+		}
+	case format.audioFormat == 1 && format.bitsPerSample == 16:
+		for i := 0; i < n; i++ {
+			v := int16(binary.LittleEndian.Uint16(raw[i*2 : i*2+2]))
+			out[i] = float32(v) / 32768.0
+		}
+	case format.audioFormat == 1 && format.bitsPerSample == 24:
+		for i := 0; i < n; i++ {
+			b := raw[i*3 : i*3+3]
+			v := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16
+			if v&0x800000 != 0 {
+				v |= ^int32(0xFFFFFF) // sign-extend
+			}
+			out[i] = float32(v) / 8388608.0
+		}
+	case format.audioFormat == 1 && format.bitsPerSample == 32:
+		for i := 0; i < n; i++ {
+			v := int32(binary.LittleEndian.Uint32(raw[i*4 : i*4+4]))
+			out[i] = float32(v) / 2147483648.0
+		}
+	default:
+		return nil, fmt.Errorf("audio: unsupported WAV format (fmt=%d, bits=%d)", format.audioFormat, format.bitsPerSample)
+	}
+	return out, nil
+}
+
+// wavStreamDecoder buffers the raw data chunk and hands out blockSize-frame
+// chunks as callers ask for them.
+type wavStreamDecoder struct {
+	format wavFormat
+	data   io.Reader
+}
+
+func newWAVStreamDecoder(r io.Reader) (streamDecoder, int, error) {
+	format, data, _, err := readWAVHeader(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &wavStreamDecoder{format: format, data: data}, int(format.sampleRate), nil
+}
+
+func (d *wavStreamDecoder) readBlock(n int) ([]float32, error) {
+	bytesPerSample := int(d.format.bitsPerSample) / 8
+	channels := int(d.format.channels)
+	raw := make([]byte, n*channels*bytesPerSample)
+	read, err := io.ReadFull(d.data, raw)
+	if read == 0 {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, err
+	}
+	raw = raw[:read-read%(channels*bytesPerSample)]
+	interleaved, convErr := wavBytesToFloat32(raw, d.format)
+	if convErr != nil {
+		return nil, convErr
+	}
+	block := downmix(interleaved, channels)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return block, err
+}
@@ -0,0 +1,23 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestReadWAVHeaderShortFmtChunk checks that a fmt chunk declaring fewer
+// than 16 bytes (the minimum readWAVHeader indexes into) returns an error
+// instead of panicking on an out-of-bounds slice.
+func TestReadWAVHeaderShortFmtChunk(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	buf.Write([]byte{0, 0, 0, 0}) // RIFF size, unused by readWAVHeader
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	buf.Write([]byte{2, 0, 0, 0}) // chunkSize = 2, short of the 16 bytes read
+	buf.Write([]byte{1, 0})       // 2-byte fmt body
+
+	if _, _, _, err := readWAVHeader(&buf); err == nil {
+		t.Fatal("expected an error for a short fmt chunk, got nil")
+	}
+}
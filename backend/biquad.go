@@ -0,0 +1,130 @@
+package main
+
+import "math"
+
+// Biquad is a single RBJ-cookbook second-order IIR filter section, applied
+// in Direct Form I. Mirrors the design used by Ardour's dsp_filter: one
+// small struct per filter shape, cheap enough to build fresh per analysis
+// pass rather than pooling them.
+type Kind int
+
+const (
+	LowPass Kind = iota
+	HighPass
+	BandPass
+	Peaking
+	LowShelf
+	HighShelf
+)
+
+type Biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+
+	// Direct Form I state: previous two input and output samples.
+	x1, x2 float64
+	y1, y2 float64
+}
+
+// NewBiquad builds a biquad of the given kind using the standard RBJ
+// Audio-EQ-Cookbook coefficient formulas. gainDB only applies to Peaking /
+// LowShelf / HighShelf; it's ignored for the other shapes.
+func NewBiquad(sr int, kind Kind, freq, q, gainDB float64) *Biquad {
+	if q <= 0 {
+		q = 0.707
+	}
+	w0 := 2 * math.Pi * freq / float64(sr)
+	cosW0 := math.Cos(w0)
+	sinW0 := math.Sin(w0)
+	alpha := sinW0 / (2 * q)
+	a := math.Pow(10, gainDB/40)
+
+	var b0, b1, b2, a0, a1, a2 float64
+	switch kind {
+	case LowPass:
+		b0 = (1 - cosW0) / 2
+		b1 = 1 - cosW0
+		b2 = (1 - cosW0) / 2
+		a0 = 1 + alpha
+		a1 = -2 * cosW0
+		a2 = 1 - alpha
+	case HighPass:
+		b0 = (1 + cosW0) / 2
+		b1 = -(1 + cosW0)
+		b2 = (1 + cosW0) / 2
+		a0 = 1 + alpha
+		a1 = -2 * cosW0
+		a2 = 1 - alpha
+	case BandPass:
+		b0 = alpha
+		b1 = 0
+		b2 = -alpha
+		a0 = 1 + alpha
+		a1 = -2 * cosW0
+		a2 = 1 - alpha
+	case Peaking:
+		b0 = 1 + alpha*a
+		b1 = -2 * cosW0
+		b2 = 1 - alpha*a
+		a0 = 1 + alpha/a
+		a1 = -2 * cosW0
+		a2 = 1 - alpha/a
+	case LowShelf:
+		sqrtA := math.Sqrt(a)
+		b0 = a * ((a + 1) - (a-1)*cosW0 + 2*sqrtA*alpha)
+		b1 = 2 * a * ((a - 1) - (a+1)*cosW0)
+		b2 = a * ((a + 1) - (a-1)*cosW0 - 2*sqrtA*alpha)
+		a0 = (a + 1) + (a-1)*cosW0 + 2*sqrtA*alpha
+		a1 = -2 * ((a - 1) + (a+1)*cosW0)
+		a2 = (a + 1) + (a-1)*cosW0 - 2*sqrtA*alpha
+	case HighShelf:
+		sqrtA := math.Sqrt(a)
+		b0 = a * ((a + 1) + (a-1)*cosW0 + 2*sqrtA*alpha)
+		b1 = -2 * a * ((a - 1) + (a+1)*cosW0)
+		b2 = a * ((a + 1) + (a-1)*cosW0 - 2*sqrtA*alpha)
+		a0 = (a + 1) - (a-1)*cosW0 + 2*sqrtA*alpha
+		a1 = 2 * ((a - 1) - (a+1)*cosW0)
+		a2 = (a + 1) - (a-1)*cosW0 - 2*sqrtA*alpha
+	}
+
+	return &Biquad{
+		b0: b0 / a0, b1: b1 / a0, b2: b2 / a0,
+		a1: a1 / a0, a2: a2 / a0,
+	}
+}
+
+// Process filters in into out sample-by-sample, carrying state across
+// calls so a caller can stream a track through in chunks. in and out may
+// alias.
+func (b *Biquad) Process(in, out []float32) {
+	for i, x := range in {
+		xf := float64(x)
+		y := b.b0*xf + b.b1*b.x1 + b.b2*b.x2 - b.a1*b.y1 - b.a2*b.y2
+		b.x2, b.x1 = b.x1, xf
+		b.y2, b.y1 = b.y1, y
+		out[i] = float32(y)
+	}
+}
+
+// Filterbank runs several independent biquads over the same input, e.g.
+// splitting a signal into sub-bass/mid/high bands for band-limited onset
+// detection.
+type Filterbank struct {
+	filters []*Biquad
+}
+
+func NewFilterbank(filters ...*Biquad) *Filterbank {
+	return &Filterbank{filters: filters}
+}
+
+// Process returns one output band per filter in the bank, each the full
+// length of in.
+func (fb *Filterbank) Process(in []float32) [][]float32 {
+	outs := make([][]float32, len(fb.filters))
+	for i, f := range fb.filters {
+		out := make([]float32, len(in))
+		f.Process(in, out)
+		outs[i] = out
+	}
+	return outs
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// renderCacheTTL bounds how long a normalized WAV or PCM chunk cache entry
+// survives under cacheDir/norm and cacheDir/chunks — long enough to span a
+// typical "nudge a transition and re-render" editing session, short enough
+// that the cache doesn't grow unbounded across many unrelated mixes.
+const renderCacheTTL = 7 * 24 * time.Hour
+
+// renderCacheKey muxes arbitrary parts (a file-content hash plus whatever
+// decode/DSP parameters select it) into one cache key — md5 over a joined
+// representation, the same hash fileHash already uses for file content;
+// this isn't a security boundary, just a cheap stable identity.
+func renderCacheKey(parts ...string) string {
+	h := md5.New()
+	io.WriteString(h, strings.Join(parts, "|"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normCacheHash identifies a track's normalized-audio content: the source
+// file hash plus whichever pathway produced the PCM that feeds the chunk
+// extraction below (ffmpeg's loudnorm filter string, or the literal
+// "native-decode" tag for tracks that skip ffmpeg entirely in
+// backend/audio/format) — the two pathways must hash differently even for
+// the same srcHash, since they produce differently-processed PCM.
+func normCacheHash(srcHash, pathway string) string {
+	return renderCacheKey(srcHash, pathway, "ar=44100", "ac=2")
+}
+
+// normCacheWavPath returns where a normalized WAV for normHash would live
+// under cacheDir/norm. RenderFinalMix's normalization pre-pass stats this
+// path before shelling out to ffmpeg, and reuses it on a hit instead of
+// re-running loudnorm.
+func normCacheWavPath(cacheDir, normHash string) string {
+	return filepath.Join(cacheDir, "norm", normHash+".wav")
+}
+
+// chunkCachePath returns where a fully-processed (trimmed, gained, faded)
+// per-track PCM chunk would live under cacheDir/chunks, keyed off the
+// track's norm hash plus every parameter that can change its output — so
+// nudging one transition's fade duration only invalidates that track's
+// chunk, not the whole mix.
+func chunkCachePath(cacheDir, normHash string, startSec, endSec, gainDB float64, entryType string, entryFade float64, exitType string, exitFade float64) string {
+	key := renderCacheKey(normHash,
+		fmt.Sprintf("%.3f", startSec), fmt.Sprintf("%.3f", endSec), fmt.Sprintf("%.3f", gainDB),
+		entryType, fmt.Sprintf("%.3f", entryFade), exitType, fmt.Sprintf("%.3f", exitFade))
+	return filepath.Join(cacheDir, "chunks", key+".f32")
+}
+
+// readCachedChunk loads a chunkCachePath file back into interleaved stereo
+// float32 samples, or reports ok=false on any read/size problem.
+func readCachedChunk(path string) (samples []float32, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data)%4 != 0 {
+		return nil, false
+	}
+	samples = make([]float32, len(data)/4)
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4 : i*4+4]))
+	}
+	return samples, true
+}
+
+// writeCachedChunk persists samples to path for a future chunkCachePath hit.
+// Best-effort: a write failure only costs a future cache miss, not the render.
+func writeCachedChunk(path string, samples []float32) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("Warning: failed to create chunk cache dir: %v", err)
+		return
+	}
+	buf := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		log.Printf("Warning: failed to write chunk cache %s: %v", path, err)
+	}
+}
+
+// sweepRenderCacheTTL deletes norm/chunk cache entries older than
+// renderCacheTTL. Called from handleCacheClear so a manual "clear cache"
+// also reclaims this disk space, without nuking entries from a render the
+// user might still be actively iterating on within the TTL window.
+func sweepRenderCacheTTL(cacheDir string) {
+	cutoff := time.Now().Add(-renderCacheTTL)
+	for _, sub := range []string{"norm", "chunks"} {
+		dir := filepath.Join(cacheDir, sub)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || info.ModTime().After(cutoff) {
+				continue
+			}
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
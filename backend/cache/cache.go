@@ -0,0 +1,186 @@
+// Package cache is djbot's persistent, content-hash-keyed store for
+// analyzed tracks: a SQLite database replacing the old one-JSON-file-per-hash
+// cache directory, so re-analyzing a renamed or moved file (same bytes, same
+// hash) is still a cache hit, and so GET /library/search can filter across
+// the whole library with one SQL query instead of reading every cached file.
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Record is one cached analysis row. Blob is the caller's own serialized
+// form of a track's full analysis (gzip-compressed JSON, built by
+// backend/analyzer.go) — this package doesn't know about backend's
+// TrackAnalysis type, to avoid an import cycle with package main.
+// BPM/Key/Duration/LoudnessDB are pulled out into real columns so Search can
+// filter without decompressing a single Blob it doesn't need.
+type Record struct {
+	Hash       string
+	BPM        float64
+	Key        string
+	Duration   float64
+	LoudnessDB float64
+	Blob       []byte
+}
+
+// Cache wraps a single SQLite database file holding one "analysis" table.
+type Cache struct {
+	db *sql.DB
+}
+
+// Open creates path's parent directory and the analysis table if either
+// doesn't exist yet, and returns a ready-to-use Cache.
+func Open(path string) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("cache: mkdir: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("cache: open: %w", err)
+	}
+	// modernc.org/sqlite serializes writers at the database-file level, so
+	// a pool of concurrent connections just contends on the same lock —
+	// one connection avoids that contention instead of hiding it.
+	db.SetMaxOpenConns(1)
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS analysis (
+	hash        TEXT PRIMARY KEY,
+	bpm         REAL,
+	key         TEXT,
+	duration    REAL,
+	loudness_db REAL,
+	blob        BLOB NOT NULL,
+	updated_at  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_analysis_bpm ON analysis(bpm);
+CREATE INDEX IF NOT EXISTS idx_analysis_key ON analysis(key);
+`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cache: migrate schema: %w", err)
+	}
+	return &Cache{db: db}, nil
+}
+
+// Close releases the underlying database connection.
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+// Get looks up hash, the content hash AnalyzeTrack keys its cache on (see
+// fileHash in backend/analyzer.go) — a renamed or moved file with identical
+// bytes still hits here, unlike the old path-derived cache file name.
+func (c *Cache) Get(hash string) (Record, bool) {
+	row := c.db.QueryRow(`SELECT hash, bpm, key, duration, loudness_db, blob FROM analysis WHERE hash = ?`, hash)
+	var rec Record
+	if err := row.Scan(&rec.Hash, &rec.BPM, &rec.Key, &rec.Duration, &rec.LoudnessDB, &rec.Blob); err != nil {
+		return Record{}, false
+	}
+	return rec, true
+}
+
+// Put upserts rec, replacing any existing row for the same hash.
+func (c *Cache) Put(rec Record) error {
+	_, err := c.db.Exec(`
+INSERT INTO analysis (hash, bpm, key, duration, loudness_db, blob, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(hash) DO UPDATE SET
+	bpm = excluded.bpm, key = excluded.key, duration = excluded.duration,
+	loudness_db = excluded.loudness_db, blob = excluded.blob, updated_at = excluded.updated_at`,
+		rec.Hash, rec.BPM, rec.Key, rec.Duration, rec.LoudnessDB, rec.Blob, time.Now().Unix())
+	return err
+}
+
+// SearchParams filters Search's query; a zero field means "don't filter on
+// this". BPMMin/BPMMax bound bpm inclusively; Key matches exactly (e.g. the
+// Camelot notation detectKey produces, "8A").
+type SearchParams struct {
+	BPMMin float64
+	BPMMax float64
+	Key    string
+}
+
+// Search runs a filtered library-wide query for GET /library/search.
+func (c *Cache) Search(p SearchParams) ([]Record, error) {
+	query := `SELECT hash, bpm, key, duration, loudness_db, blob FROM analysis WHERE 1=1`
+	var args []interface{}
+	if p.BPMMin > 0 {
+		query += ` AND bpm >= ?`
+		args = append(args, p.BPMMin)
+	}
+	if p.BPMMax > 0 {
+		query += ` AND bpm <= ?`
+		args = append(args, p.BPMMax)
+	}
+	if p.Key != "" {
+		query += ` AND key = ?`
+		args = append(args, p.Key)
+	}
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cache: search: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Record
+	for rows.Next() {
+		var rec Record
+		if err := rows.Scan(&rec.Hash, &rec.BPM, &rec.Key, &rec.Duration, &rec.LoudnessDB, &rec.Blob); err != nil {
+			return nil, fmt.Errorf("cache: scan: %w", err)
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// MigrateFromJSON imports every "<hash>_analysis.json" file in dir (the old
+// file-based cache's naming convention) not already present in the
+// database, and returns how many rows it added. decode re-encodes each
+// file's raw JSON into this cache's Record shape without this package
+// needing to know the JSON's Go type.
+func (c *Cache) MigrateFromJSON(dir string, decode func(jsonData []byte) (Record, error)) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("cache: migrate: readdir: %w", err)
+	}
+
+	n := 0
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, "_analysis.json") {
+			continue
+		}
+		hash := strings.TrimSuffix(name, "_analysis.json")
+		if _, ok := c.Get(hash); ok {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		rec, err := decode(data)
+		if err != nil {
+			continue
+		}
+		rec.Hash = hash
+		if err := c.Put(rec); err != nil {
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
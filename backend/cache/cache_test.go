@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestCache(t *testing.T) *Cache {
+	t.Helper()
+	c, err := Open(filepath.Join(t.TempDir(), "analysis.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestPutGetRoundTrip(t *testing.T) {
+	c := openTestCache(t)
+
+	want := Record{Hash: "abc123", BPM: 128, Key: "8A", Duration: 180.5, LoudnessDB: -14.2, Blob: []byte("fake blob")}
+	if err := c.Put(want); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok := c.Get("abc123")
+	if !ok {
+		t.Fatal("Get: expected a hit after Put")
+	}
+	if got.Hash != want.Hash || got.BPM != want.BPM || got.Key != want.Key ||
+		got.Duration != want.Duration || got.LoudnessDB != want.LoudnessDB || string(got.Blob) != string(want.Blob) {
+		t.Fatalf("Get: got %+v, want %+v", got, want)
+	}
+
+	if _, ok := c.Get("does-not-exist"); ok {
+		t.Fatal("Get: expected a miss for an unknown hash")
+	}
+}
+
+func TestSearchFiltersByBPMAndKey(t *testing.T) {
+	c := openTestCache(t)
+
+	records := []Record{
+		{Hash: "slow", BPM: 90, Key: "8A", Blob: []byte("x")},
+		{Hash: "mid", BPM: 120, Key: "8A", Blob: []byte("x")},
+		{Hash: "fast", BPM: 140, Key: "9A", Blob: []byte("x")},
+	}
+	for _, r := range records {
+		if err := c.Put(r); err != nil {
+			t.Fatalf("Put(%s): %v", r.Hash, err)
+		}
+	}
+
+	got, err := c.Search(SearchParams{BPMMin: 100, BPMMax: 130})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(got) != 1 || got[0].Hash != "mid" {
+		t.Fatalf("Search by BPM range: got %+v, want only \"mid\"", got)
+	}
+
+	got, err = c.Search(SearchParams{Key: "8A"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Search by key: got %d results, want 2", len(got))
+	}
+}
+
+// TestMigrateFromJSONRoundTrip writes the old file-based cache's
+// "<hash>_analysis.json" layout to a temp dir and checks that
+// MigrateFromJSON imports each file exactly once, decoded through the
+// caller-supplied decode callback, and skips hashes already present.
+func TestMigrateFromJSONRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"hash1_analysis.json": `{"bpm":128,"key":"8A"}`,
+		"hash2_analysis.json": `{"bpm":140,"key":"9A"}`,
+		"not_analysis_file":   `{"bpm":999}`, // wrong suffix, must be ignored
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatalf("WriteFile(%s): %v", name, err)
+		}
+	}
+
+	decode := func(data []byte) (Record, error) {
+		var doc struct {
+			BPM float64 `json:"bpm"`
+			Key string  `json:"key"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return Record{}, err
+		}
+		return Record{BPM: doc.BPM, Key: doc.Key, Blob: data}, nil
+	}
+
+	c := openTestCache(t)
+	n, err := c.MigrateFromJSON(dir, decode)
+	if err != nil {
+		t.Fatalf("MigrateFromJSON: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("MigrateFromJSON: imported %d records, want 2", n)
+	}
+
+	got, ok := c.Get("hash1")
+	if !ok {
+		t.Fatal("expected hash1 to be migrated")
+	}
+	if got.BPM != 128 || got.Key != "8A" {
+		t.Fatalf("hash1: got %+v, want bpm=128 key=8A", got)
+	}
+	if _, ok := c.Get("not_analysis_file"); ok {
+		t.Fatal("did not expect a record for the non-\"_analysis.json\" file")
+	}
+
+	// Re-running MigrateFromJSON against the same dir must not re-import
+	// hashes already present.
+	n, err = c.MigrateFromJSON(dir, decode)
+	if err != nil {
+		t.Fatalf("MigrateFromJSON (second run): %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("MigrateFromJSON (second run): imported %d records, want 0 (already migrated)", n)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// defaultPresignExpiry is how long a presigned GET URL stays valid when the
+// caller doesn't specify PresignExpirySeconds.
+const defaultPresignExpiry = 24 * time.Hour
+
+// s3MultipartPartSize sits in the 5-15MB range CompleteMultipartUpload
+// requires for every part but the last.
+const s3MultipartPartSize = 8 * 1024 * 1024
+
+// CloudExportRequest uploads the same artifact handleExportZip would
+// zip/tag locally straight to an S3-compatible bucket instead, returning a
+// presigned GET URL. Endpoint/Region let this target MinIO/R2/Backblaze as
+// well as AWS itself.
+type CloudExportRequest struct {
+	ExportZipRequest
+
+	Bucket               string `json:"bucket"`
+	KeyTemplate          string `json:"key_template,omitempty"` // supports {mix_name}, {filename}, {date}
+	Endpoint             string `json:"endpoint,omitempty"`
+	Region               string `json:"region,omitempty"`
+	AccessKeyID          string `json:"access_key_id,omitempty"`
+	SecretAccessKey      string `json:"secret_access_key,omitempty"`
+	PresignExpirySeconds int    `json:"presign_expiry_seconds,omitempty"`
+}
+
+type CloudExportResponse struct {
+	URL   string `json:"url"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleCloudExport handles POST /api/export/upload: builds the export
+// artifact (zip or embed, same as handleExportZip) and streams it straight
+// into an S3 multipart upload without buffering the whole mix to disk,
+// then hands back a presigned GET URL.
+func handleCloudExport(w http.ResponseWriter, r *http.Request) {
+	var req CloudExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Mp3Path == "" || req.Bucket == "" {
+		http.Error(w, "mp3_path and bucket required", http.StatusBadRequest)
+		return
+	}
+	if !req.Embed && req.LrcPath == "" {
+		http.Error(w, "lrc_path required unless embed is set", http.StatusBadRequest)
+		return
+	}
+	if req.Embed && req.Format != "" && req.Format != "mp3" {
+		http.Error(w, "embed mode only supports mp3 output", http.StatusBadRequest)
+		return
+	}
+
+	baseName := req.MixName
+	if baseName == "" {
+		baseName = "AutoMix"
+	}
+	safeName := filepath.Base(baseName)
+	if ext := filepath.Ext(safeName); ext != "" {
+		safeName = safeName[:len(safeName)-len(ext)]
+	}
+
+	audioPath := req.Mp3Path
+	if req.Format != "" && req.Format != "mp3" {
+		transcoded, err := transcodeAudio(req.Mp3Path, req.Format, req.BitrateKbps, req.FlacCompressionLevel, cacheDir, nil)
+		if err != nil {
+			http.Error(w, "transcode failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(transcoded)
+		audioPath = transcoded
+	}
+
+	body, filename, contentType, err := buildExportReader(req.ExportZipRequest, audioPath, safeName)
+	if err != nil {
+		http.Error(w, "failed to build export: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	ctx := r.Context()
+	client, err := newS3Client(ctx, req.Endpoint, req.Region, req.AccessKeyID, req.SecretAccessKey)
+	if err != nil {
+		http.Error(w, "s3 client: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	key := resolveExportKey(req.KeyTemplate, safeName, filename)
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = s3MultipartPartSize
+	})
+	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(req.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Printf("cloud export: upload aborted by client: %v", err)
+		} else {
+			log.Printf("cloud export: upload failed: %v", err)
+		}
+		json.NewEncoder(w).Encode(CloudExportResponse{Error: err.Error()})
+		return
+	}
+
+	expiry := defaultPresignExpiry
+	if req.PresignExpirySeconds > 0 {
+		expiry = time.Duration(req.PresignExpirySeconds) * time.Second
+	}
+	presignClient := s3.NewPresignClient(client)
+	presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(req.Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		json.NewEncoder(w).Encode(CloudExportResponse{Error: "upload succeeded but presign failed: " + err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(CloudExportResponse{URL: presigned.URL})
+}
+
+// newS3Client builds an S3 client, optionally pointed at a custom endpoint
+// (MinIO / Cloudflare R2 / Backblaze B2 all speak the S3 API) and using
+// request-supplied static credentials when given, falling back to the
+// ambient AWS config chain (env vars, shared config, instance role) when
+// not.
+func newS3Client(ctx context.Context, endpoint, region, accessKeyID, secretAccessKey string) (*s3.Client, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	if accessKeyID != "" && secretAccessKey != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, ""),
+		))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true // required by MinIO and most other S3-compatible endpoints
+		}
+	}), nil
+}
+
+// resolveExportKey substitutes {mix_name}/{filename}/{date} placeholders in
+// template, defaulting to "exports/<mix_name>/<filename>" when no template
+// is given.
+func resolveExportKey(template, mixName, filename string) string {
+	if template == "" {
+		return fmt.Sprintf("exports/%s/%s", mixName, filename)
+	}
+	key := strings.ReplaceAll(template, "{mix_name}", mixName)
+	key = strings.ReplaceAll(key, "{filename}", filename)
+	key = strings.ReplaceAll(key, "{date}", time.Now().Format("2006-01-02"))
+	return key
+}
+
+// buildExportReader produces the export artifact (tagged MP3 in embed mode,
+// zip of audio+lrc otherwise) as a streaming io.ReadCloser, so
+// handleCloudExport can pipe it straight into the S3 multipart uploader
+// instead of buffering the whole mix on disk first.
+func buildExportReader(req ExportZipRequest, audioPath, safeName string) (io.ReadCloser, string, string, error) {
+	if req.Embed {
+		tagBytes := writeID3v2Tag(buildEmbedFrames(req))
+
+		audioFile, err := os.Open(audioPath)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("open audio: %w", err)
+		}
+		reader := io.MultiReader(bytes.NewReader(tagBytes), audioFile)
+		return &readCloserWithUnderlying{Reader: reader, closer: audioFile}, safeName + ".mp3", "audio/mpeg", nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		zw := zip.NewWriter(pw)
+		if err := addFileToZip(zw, audioPath, safeName+exportFormatExt(req.Format)); err != nil {
+			zw.Close()
+			pw.CloseWithError(err)
+			return
+		}
+		if err := addFileToZip(zw, req.LrcPath, safeName+".lrc"); err != nil {
+			zw.Close()
+			pw.CloseWithError(err)
+			return
+		}
+		if err := zw.Close(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.Close()
+	}()
+	return pr, safeName + ".zip", "application/zip", nil
+}
+
+// readCloserWithUnderlying adapts an io.Reader (here, a MultiReader whose
+// only closeable component is the trailing audio file) to io.ReadCloser.
+type readCloserWithUnderlying struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r *readCloserWithUnderlying) Close() error {
+	return r.closer.Close()
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +14,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -29,6 +33,11 @@ var ytdlpBinDir string
 // ytdlpDownloading is true while an initial background download is in progress.
 var ytdlpDownloading atomic.Bool
 
+// headlessBuild disables the browser-cookie-autodiscovery retry stages
+// (no desktop browser profiles exist to find on a headless server). Set via
+// the DJBOT_HEADLESS env var.
+var headlessBuild = os.Getenv("DJBOT_HEADLESS") != ""
+
 func getYtdlpPath() string {
 	ytdlpMu.RLock()
 	defer ytdlpMu.RUnlock()
@@ -114,7 +123,14 @@ func initYtdlp() {
 
 // tryAutoUpdateYtdlp runs yt-dlp -U if the binary is writable. If it is a
 // read-only system install, a fresh copy is downloaded into the managed dir.
+// A pinned YtdlpVersion disables this entirely — an operator who pinned a
+// known-good version doesn't want it silently replaced by `-U`.
 func tryAutoUpdateYtdlp() {
+	if ytdlpVersionPin != "" {
+		log.Printf("[yt-dlp] version pinned to %s — skipping auto-update", ytdlpVersionPin)
+		return
+	}
+
 	cur := getYtdlpPath()
 	if cur == "" {
 		return
@@ -154,8 +170,118 @@ func tryAutoUpdateYtdlp() {
 	}
 }
 
-// downloadYtdlp fetches the latest yt-dlp release for the current OS/arch
-// and saves it into dir with the correct executable permissions.
+// ytdlpReleaseRepo maps a YtdlpChannel to the GitHub repo that publishes it.
+var ytdlpReleaseRepo = map[string]string{
+	"stable":  "yt-dlp/yt-dlp",
+	"nightly": "yt-dlp/yt-dlp-nightly-builds",
+	"master":  "yt-dlp/yt-dlp-master-builds",
+}
+
+// ytdlpChannel/ytdlpVersionPin select which release stream downloadYtdlp and
+// tryAutoUpdateYtdlp pull from. Set by main() from flags/env; empty channel
+// defaults to "stable", empty pin means "always take the latest".
+var ytdlpChannel = "stable"
+var ytdlpVersionPin = ""
+
+// ytdlpReleaseBaseURL returns the GitHub release download base for the
+// configured channel/pin, shared by the binary, its checksum manifest, and
+// its detached signature. With no version pin this is the repo's "latest"
+// release; with a pin (e.g. "2024.05.27") it targets that exact tag so an
+// extractor regression in a newer release can't silently reach users.
+func ytdlpReleaseBaseURL() string {
+	channel := ytdlpChannel
+	if channel == "" {
+		channel = "stable"
+	}
+	repo := ytdlpReleaseRepo[channel]
+	if repo == "" {
+		repo = ytdlpReleaseRepo["stable"]
+	}
+	if ytdlpVersionPin == "" {
+		return fmt.Sprintf("https://github.com/%s/releases/latest/download/", repo)
+	}
+	return fmt.Sprintf("https://github.com/%s/releases/download/%s/", repo, ytdlpVersionPin)
+}
+
+// ytdlpPubKeyPath, if set, points at an ASCII-armored OpenPGP public key
+// bundled with the installer. When present, downloadYtdlp additionally
+// verifies SHA2-256SUMS.sig before trusting the checksum file itself.
+var ytdlpPubKeyPath string
+
+// fetchYtdlpChecksum downloads SHA2-256SUMS from the release, optionally
+// verifies its detached signature, and returns the expected hex digest for
+// assetName, erroring if the line is missing.
+func fetchYtdlpChecksum(client *http.Client, assetName string) (string, error) {
+	resp, err := client.Get(ytdlpReleaseBaseURL() + "SHA2-256SUMS")
+	if err != nil {
+		return "", fmt.Errorf("GET SHA2-256SUMS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET SHA2-256SUMS: HTTP %d", resp.StatusCode)
+	}
+
+	sums, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read SHA2-256SUMS: %w", err)
+	}
+
+	if err := verifyYtdlpSignature(client, sums); err != nil {
+		return "", fmt.Errorf("SHA2-256SUMS signature: %w", err)
+	}
+
+	for _, line := range strings.Split(string(sums), "\n") {
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == assetName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum entry for %s in SHA2-256SUMS", assetName)
+}
+
+// verifyYtdlpSignature checks the detached signature over SHA2-256SUMS
+// against ytdlpPubKeyPath, when one has been configured. It is best-effort:
+// if no key is bundled we simply skip this extra layer and rely on the
+// SHA-256 check alone.
+func verifyYtdlpSignature(client *http.Client, sums []byte) error {
+	if ytdlpPubKeyPath == "" {
+		return nil
+	}
+	keyring, err := os.Open(ytdlpPubKeyPath)
+	if err != nil {
+		return fmt.Errorf("open pubkey: %w", err)
+	}
+	defer keyring.Close()
+
+	resp, err := client.Get(ytdlpReleaseBaseURL() + "SHA2-256SUMS.sig")
+	if err != nil {
+		return fmt.Errorf("GET SHA2-256SUMS.sig: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET SHA2-256SUMS.sig: HTTP %d", resp.StatusCode)
+	}
+	sig, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read SHA2-256SUMS.sig: %w", err)
+	}
+
+	return checkOpenPGPSignature(keyring, bytes.NewReader(sums), sig)
+}
+
+// downloadYtdlp fetches the yt-dlp release for the current OS/arch from the
+// configured channel/version pin, verifies it against the published
+// SHA2-256SUMS (and, when a bundled public key is available, the detached
+// SHA2-256SUMS.sig), and saves it into dir with the correct executable
+// permissions. A checksum mismatch aborts before the temp file is ever
+// renamed into place, so a bad or tampered binary never becomes the active
+// yt-dlp. On success, the resolved version is persisted to dir/version.json,
+// which GET /ytdlp/version and POST /ytdlp/update report as the current
+// version; initYtdlp does not read it at startup.
 func downloadYtdlp(dir string) error {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("mkdir %s: %w", dir, err)
@@ -172,10 +298,16 @@ func downloadYtdlp(dir string) error {
 		assetName = "yt-dlp"
 	}
 
-	dlURL := "https://github.com/yt-dlp/yt-dlp/releases/latest/download/" + assetName
+	dlURL := ytdlpReleaseBaseURL() + assetName
 	log.Printf("[yt-dlp] downloading from %s", dlURL)
 
 	client := &http.Client{Timeout: 120 * time.Second}
+
+	wantSum, err := fetchYtdlpChecksum(client, assetName)
+	if err != nil {
+		return fmt.Errorf("checksum lookup: %w", err)
+	}
+
 	resp, err := client.Get(dlURL)
 	if err != nil {
 		return fmt.Errorf("GET: %w", err)
@@ -192,13 +324,21 @@ func downloadYtdlp(dir string) error {
 	if err != nil {
 		return fmt.Errorf("create tmp: %w", err)
 	}
-	if _, err := io.Copy(f, resp.Body); err != nil {
+	hasher := sha256.New()
+	if _, err := io.Copy(f, io.TeeReader(resp.Body, hasher)); err != nil {
 		f.Close()
 		os.Remove(tmp)
 		return fmt.Errorf("write: %w", err)
 	}
 	f.Close()
 
+	gotSum := hex.EncodeToString(hasher.Sum(nil))
+	if gotSum != wantSum {
+		os.Remove(tmp)
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s (refusing to install)", assetName, gotSum, wantSum)
+	}
+	log.Printf("[yt-dlp] checksum verified: %s", gotSum)
+
 	dest := filepath.Join(dir, destName)
 	os.Remove(dest)
 	if err := os.Rename(tmp, dest); err != nil {
@@ -209,6 +349,12 @@ func downloadYtdlp(dir string) error {
 		_ = os.Chmod(dest, 0755)
 	}
 	log.Printf("[yt-dlp] saved to %s", dest)
+
+	if v, err := ytdlpBinaryVersion(dest); err == nil {
+		saveYtdlpVersionInfo(dir, ytdlpVersionInfo{Channel: ytdlpChannel, Version: v})
+	} else {
+		log.Printf("[yt-dlp] could not determine installed version: %v", err)
+	}
 	return nil
 }
 
@@ -220,6 +366,22 @@ type DownloadRequest struct {
 	URL       string `json:"url"`
 	OutputDir string `json:"output_dir,omitempty"`
 	MaxTracks int    `json:"max_tracks,omitempty"`
+
+	// CookiesFile is a path to a Netscape-format cookies.txt (the same format
+	// yt-dlp's --cookies accepts), typically one persisted via /cookies/upload.
+	CookiesFile string `json:"cookies_file,omitempty"`
+	// CookiesFromBrowser is passed through verbatim to --cookies-from-browser,
+	// e.g. "chrome", "brave:Default", "chromium+kwallet", "safari".
+	CookiesFromBrowser string `json:"cookies_from_browser,omitempty"`
+
+	// Concurrency, when > 1, switches to the per-video worker-pool downloader
+	// in downloader_concurrent.go, with that many workers, instead of a single
+	// sequential yt-dlp invocation over the whole playlist. 0 or 1 (the
+	// default) keeps the sequential invocation.
+	Concurrency int `json:"concurrency,omitempty"`
+	// SourceAddresses lets operators with multiple local IPs round-robin
+	// outbound requests between them (bound via yt-dlp's --source-address).
+	SourceAddresses []string `json:"source_addresses,omitempty"`
 }
 
 type DownloadResponse struct {
@@ -231,16 +393,72 @@ type DownloadedFile struct {
 	Path     string `json:"path"`
 	Filename string `json:"filename"`
 	Title    string `json:"title"`
+
+	// Metadata is populated from yt-dlp's --write-info-json sidecar, when
+	// that file was produced alongside this download. Nil if parsing failed
+	// or the sidecar wasn't written (e.g. an upstream yt-dlp flag mismatch).
+	Metadata *YoutubeMetadata `json:"metadata,omitempty"`
 }
 
-// DownloadYouTubePlaylist downloads audio via yt-dlp.
+// YoutubeMetadata is the subset of yt-dlp's .info.json we care about —
+// exactly the fields the downstream TrackAnalysis/TrackEntry pipeline can put
+// to use (thumbnails for the mix UI, chapters as phrase-boundary candidates,
+// uploader/date for library metadata).
+type YoutubeMetadata struct {
+	Title      string    `json:"title"`
+	Uploader   string    `json:"uploader"`
+	ChannelID  string    `json:"channel_id"`
+	Duration   float64   `json:"duration"`
+	UploadDate string    `json:"upload_date"`
+	Categories []string  `json:"categories"`
+	Tags       []string  `json:"tags"`
+	Thumbnail  string    `json:"thumbnail"`
+	Chapters   []Chapter `json:"chapters"`
+}
+
+// DownloadEvent is a single structured progress update emitted while a
+// playlist download runs. Stage is one of: "stage" (retry stage changed),
+// "track_start", "progress", "track_done", "error", "done".
+type DownloadEvent struct {
+	Stage   string          `json:"stage"`
+	Label   string          `json:"label,omitempty"`   // retry-stage label, for Stage == "stage"
+	Title   string          `json:"title,omitempty"`   // current track title
+	Percent string          `json:"percent,omitempty"` // e.g. "42.3%"
+	Speed   string          `json:"speed,omitempty"`   // e.g. "1.21MiB/s"
+	ETA     string          `json:"eta,omitempty"`     // e.g. "00:12"
+	File    *DownloadedFile `json:"file,omitempty"`    // for Stage == "track_done"
+	Error   string          `json:"error,omitempty"`
+}
+
+// ytdlpProgressTemplate asks yt-dlp to print one "download:" line per
+// progress tick, pipe-delimited so it's trivial to split without pulling in
+// a JSON-per-line dependency.
+const ytdlpProgressTemplate = "download:%(progress._percent_str)s|%(progress._speed_str)s|%(progress._eta_str)s|%(info.title)s"
+
+// DownloadYouTubePlaylist downloads audio via yt-dlp, optionally reporting
+// structured progress on events as it goes. events may be nil, in which case
+// this behaves exactly like the old blocking, event-less call.
 //
 // Root cause of the Korean-path bug on Windows: yt-dlp outputs paths in CP949
 // but Go treats subprocess stdout as bytes and re-interprets them as UTF-8,
 // corrupting the path. Fix: force PYTHONUTF8=1 + PYTHONIOENCODING=utf-8 so
 // yt-dlp outputs real UTF-8, then use filepath.Base() for the filename and
 // re-join with the known outputDir (a correct UTF-8 string held by Go).
-func DownloadYouTubePlaylist(url, outputDir string, maxTracks int) ([]DownloadedFile, error) {
+func DownloadYouTubePlaylist(url, outputDir string, maxTracks int, cookiesFile, cookiesFromBrowser string, events chan<- DownloadEvent) ([]DownloadedFile, error) {
+	return downloadYouTube(url, outputDir, maxTracks, cookiesFile, cookiesFromBrowser, "", events)
+}
+
+// downloadYouTube is the shared implementation behind DownloadYouTubePlaylist
+// and the per-video concurrent workers in downloader_concurrent.go. sourceAddr,
+// when non-empty, binds the yt-dlp process to that local address via
+// --source-address (see downloader_concurrent.go's addressPool).
+func downloadYouTube(url, outputDir string, maxTracks int, cookiesFile, cookiesFromBrowser, sourceAddr string, events chan<- DownloadEvent) ([]DownloadedFile, error) {
+	emit := func(ev DownloadEvent) {
+		if events != nil {
+			events <- ev
+		}
+	}
+
 	if getYtdlpPath() == "" {
 		if ytdlpDownloading.Load() {
 			return nil, fmt.Errorf("yt-dlp is still downloading (first run). Please wait a moment and try again.")
@@ -279,33 +497,63 @@ func DownloadYouTubePlaylist(url, outputDir string, maxTracks int) ([]Downloaded
 		"--add-metadata",
 		"--retries", "5",
 		"--fragment-retries", "5",
+		"--newline",
+		"--progress-template", ytdlpProgressTemplate,
+		"--write-info-json",
+		"--write-thumbnail",
+		"--embed-thumbnail",
+		"--embed-chapters",
 		"--print", "after_move:filepath",
 	}
 	if maxTracks > 0 {
 		baseArgs = append(baseArgs, "--playlist-end", fmt.Sprintf("%d", maxTracks))
 	}
+	if sourceAddr != "" {
+		baseArgs = append(baseArgs, "--source-address", sourceAddr)
+	}
 
 	// ── Multi-stage retry strategy ────────────────────────────────────────
 	//
 	// YouTube 403 Forbidden errors most commonly come from:
 	//   a) Bot detection on bulk playlist downloads → add request delays
 	//   b) Authentication requirement (members-only, age-restricted)
-	//      → browser cookie fallback
+	//      → cookie fallback
 	//
 	// We stop as soon as stdout contains at least one downloaded file path.
 	type retryStage struct {
 		label     string
 		extraArgs []string
 	}
-	stages := []retryStage{
-		// Stage 1: default (fastest, works for most public playlists)
-		{label: "default", extraArgs: nil},
-		// Stage 2: add sleep between requests to avoid rate-limiting
-		{label: "slow", extraArgs: []string{"--sleep-requests", "2", "--sleep-interval", "1"}},
-		// Stage 3-5: try browser cookies (handles login-required content)
-		{label: "chrome", extraArgs: []string{"--cookies-from-browser", "chrome"}},
-		{label: "edge", extraArgs: []string{"--cookies-from-browser", "edge"}},
-		{label: "firefox", extraArgs: []string{"--cookies-from-browser", "firefox"}},
+	var stages []retryStage
+
+	// An explicit cookies.txt or --cookies-from-browser value takes priority
+	// over everything, including the default (cookie-less) attempt — if the
+	// caller configured auth, a logged-out attempt first would just waste
+	// the rate-limit budget on content we already know needs auth.
+	if cookiesFile != "" {
+		stages = append(stages, retryStage{label: "cookies-file", extraArgs: []string{"--cookies", cookiesFile}})
+	}
+	if cookiesFromBrowser != "" {
+		stages = append(stages, retryStage{label: "cookies-from-browser:" + cookiesFromBrowser, extraArgs: []string{"--cookies-from-browser", cookiesFromBrowser}})
+	}
+
+	stages = append(stages,
+		// default (fastest, works for most public playlists)
+		retryStage{label: "default", extraArgs: nil},
+		// add sleep between requests to avoid rate-limiting
+		retryStage{label: "slow", extraArgs: []string{"--sleep-requests", "2", "--sleep-interval", "1"}},
+	)
+
+	// Desktop-browser autodiscovery only makes sense when we haven't already
+	// been told how to authenticate, and is skipped entirely on headless
+	// builds (no local browser profiles to find) instead of burning three
+	// guaranteed-to-fail attempts.
+	if cookiesFile == "" && cookiesFromBrowser == "" && !headlessBuild {
+		stages = append(stages,
+			retryStage{label: "chrome", extraArgs: []string{"--cookies-from-browser", "chrome"}},
+			retryStage{label: "edge", extraArgs: []string{"--cookies-from-browser", "edge"}},
+			retryStage{label: "firefox", extraArgs: []string{"--cookies-from-browser", "firefox"}},
+		)
 	}
 
 	var lastOut []byte
@@ -317,6 +565,8 @@ func DownloadYouTubePlaylist(url, outputDir string, maxTracks int) ([]Downloaded
 		args = append(args, stage.extraArgs...)
 
 		log.Printf("[yt-dlp] [%s] attempting download...", stage.label)
+		emit(DownloadEvent{Stage: "stage", Label: stage.label})
+
 		cmd := exec.Command(getYtdlpPath(), args...)
 		hideWindow(cmd)
 		// Force UTF-8 output from Python/yt-dlp on every platform.
@@ -324,7 +574,13 @@ func DownloadYouTubePlaylist(url, outputDir string, maxTracks int) ([]Downloaded
 
 		var stderr bytes.Buffer
 		cmd.Stderr = &stderr
-		out, err := cmd.Output()
+
+		out, err := runYtdlpCapturingOutput(cmd, emit)
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// cmd.Stderr is set, so Wait leaves ExitError.Stderr empty; keep
+			// the captured text for the failure message built below.
+			exitErr.Stderr = stderr.Bytes()
+		}
 		lastOut = out
 		lastErr = err
 
@@ -340,7 +596,9 @@ func DownloadYouTubePlaylist(url, outputDir string, maxTracks int) ([]Downloaded
 	out := lastOut
 	err := lastErr
 
-	// Parse stdout: each line is an absolute path printed by --print after_move:filepath
+	// Parse stdout: each line is either a "download:" progress update (already
+	// consumed by runYtdlpCapturingOutput) or an absolute path printed by
+	// --print after_move:filepath.
 	var files []DownloadedFile
 	seen := map[string]bool{}
 	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
@@ -361,21 +619,91 @@ func DownloadYouTubePlaylist(url, outputDir string, maxTracks int) ([]Downloaded
 		}
 		title := strings.TrimSuffix(name, filepath.Ext(name))
 		title = strings.ReplaceAll(title, "_", " ")
-		files = append(files, DownloadedFile{Path: absPath, Filename: name, Title: title})
+		file := DownloadedFile{Path: absPath, Filename: name, Title: title}
+		if meta, err := loadYoutubeMetadata(absPath); err == nil {
+			file.Metadata = meta
+		} else {
+			log.Printf("[yt-dlp] no metadata sidecar for %s: %v", name, err)
+		}
+		files = append(files, file)
+		emit(DownloadEvent{Stage: "track_done", Title: title, File: &file})
 		log.Printf("[yt-dlp] ready: %s", name)
 	}
 
 	if len(files) == 0 && err != nil {
+		var errMsg string
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("yt-dlp failed after all attempts: %w\n%s", err, string(exitErr.Stderr))
+			errMsg = fmt.Sprintf("yt-dlp failed after all attempts: %v\n%s", err, string(exitErr.Stderr))
+		} else {
+			errMsg = fmt.Sprintf("yt-dlp failed after all attempts: %v", err)
 		}
-		return nil, fmt.Errorf("yt-dlp failed after all attempts: %w", err)
+		emit(DownloadEvent{Stage: "error", Error: errMsg})
+		return nil, fmt.Errorf("%s", errMsg)
 	}
 
+	emit(DownloadEvent{Stage: "done"})
 	return files, nil
 }
 
-// handleDownloadYouTube handles POST /download/youtube
+// loadYoutubeMetadata reads the .info.json sidecar yt-dlp writes next to
+// mediaPath (via --write-info-json) and parses it into a YoutubeMetadata.
+func loadYoutubeMetadata(mediaPath string) (*YoutubeMetadata, error) {
+	sidecar := strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath)) + ".info.json"
+	data, err := os.ReadFile(sidecar)
+	if err != nil {
+		return nil, err
+	}
+	var meta YoutubeMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", sidecar, err)
+	}
+	return &meta, nil
+}
+
+// runYtdlpCapturingOutput runs cmd to completion, capturing every non-progress
+// line of stdout (the `after_move:filepath` lines DownloadYouTubePlaylist
+// parses afterwards) while emitting a track_start/progress event for each
+// "download:"-prefixed progress line as it's read, instead of waiting for the
+// whole process to exit. emit may be a no-op (nil events channel upstream).
+func runYtdlpCapturingOutput(cmd *exec.Cmd, emit func(DownloadEvent)) ([]byte, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+
+	var captured bytes.Buffer
+	var lastTitle string
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if rest, ok := strings.CutPrefix(line, "download:"); ok {
+			fields := strings.SplitN(rest, "|", 4)
+			for len(fields) < 4 {
+				fields = append(fields, "")
+			}
+			percent, speed, eta, title := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1]), strings.TrimSpace(fields[2]), strings.TrimSpace(fields[3])
+			if title != lastTitle {
+				emit(DownloadEvent{Stage: "track_start", Title: title})
+				lastTitle = title
+			}
+			emit(DownloadEvent{Stage: "progress", Title: title, Percent: percent, Speed: speed, ETA: eta})
+			continue
+		}
+		captured.WriteString(line)
+		captured.WriteByte('\n')
+	}
+
+	waitErr := cmd.Wait()
+	return captured.Bytes(), waitErr
+}
+
+// handleDownloadYouTube handles POST /download/youtube. It stays a thin,
+// synchronous wrapper around DownloadYouTubePlaylist with a nil events
+// channel; see handleDownloadYouTubeStream for the SSE variant.
 func handleDownloadYouTube(w http.ResponseWriter, r *http.Request) {
 	var req DownloadRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -391,7 +719,23 @@ func handleDownloadYouTube(w http.ResponseWriter, r *http.Request) {
 	}
 
 	absUploads, _ := filepath.Abs(uploadsDir)
-	files, err := DownloadYouTubePlaylist(req.URL, absUploads, req.MaxTracks)
+
+	if req.Concurrency > 1 {
+		files, errs, err := DownloadYouTubePlaylistConcurrent(req, absUploads, nil)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			json.NewEncoder(w).Encode(DownloadResponse{Error: err.Error()})
+			return
+		}
+		resp := DownloadResponse{Files: files}
+		if len(errs) > 0 {
+			resp.Error = strings.Join(errs, "; ")
+		}
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	files, err := DownloadYouTubePlaylist(req.URL, absUploads, req.MaxTracks, req.CookiesFile, req.CookiesFromBrowser, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err != nil {
@@ -400,3 +744,127 @@ func handleDownloadYouTube(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewEncoder(w).Encode(DownloadResponse{Files: files})
 }
+
+// handleDownloadYouTubeStream handles GET /download/youtube/stream, running
+// the same download but emitting each DownloadEvent as a Server-Sent Event
+// as it happens instead of blocking until every track finishes. Query params
+// mirror DownloadRequest's JSON fields (url, output_dir, max_tracks,
+// cookies_file, cookies_from_browser).
+func handleDownloadYouTubeStream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	url := q.Get("url")
+	if url == "" {
+		http.Error(w, "url is required", 400)
+		return
+	}
+	maxTracks := 30
+	if v := q.Get("max_tracks"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxTracks = n
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	absUploads, _ := filepath.Abs(uploadsDir)
+	outDir := absUploads
+	if od := q.Get("output_dir"); od != "" {
+		outDir = od
+	}
+
+	events := make(chan DownloadEvent, 16)
+	done := make(chan struct{})
+	var sawError bool
+	go func() {
+		defer close(done)
+		defer close(events)
+		internal := make(chan DownloadEvent, 16)
+		relayDone := make(chan struct{})
+		go func() {
+			defer close(relayDone)
+			for ev := range internal {
+				if ev.Stage == "error" {
+					sawError = true
+				}
+				events <- ev
+			}
+		}()
+		_, err := DownloadYouTubePlaylist(url, outDir, maxTracks, q.Get("cookies_file"), q.Get("cookies_from_browser"), internal)
+		close(internal)
+		<-relayDone
+		if err != nil && !sawError {
+			// runtime errors before the final "error" event (e.g. yt-dlp
+			// unavailable) never reached DownloadYouTubePlaylist's emit calls;
+			// for those we still need to surface something. If an "error"
+			// event already went out, DownloadYouTubePlaylist's own emit
+			// already told the client what happened — don't duplicate it.
+			events <- DownloadEvent{Stage: "error", Error: err.Error()}
+		}
+	}()
+
+	for ev := range events {
+		data, _ := json.Marshal(ev)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Stage, data)
+		flusher.Flush()
+	}
+	<-done
+}
+
+// CookiesUploadResponse reports where an uploaded cookies.txt was persisted.
+type CookiesUploadResponse struct {
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleCookiesUpload accepts a multipart-uploaded Netscape cookies.txt and
+// persists it into the managed bin dir, so it survives restarts and can be
+// referenced by path in subsequent DownloadRequest.CookiesFile fields.
+func handleCookiesUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := r.ParseMultipartForm(8 << 20); err != nil { // 8 MB is generous for a cookie jar
+		json.NewEncoder(w).Encode(CookiesUploadResponse{Error: "parse form: " + err.Error()})
+		return
+	}
+
+	file, _, err := r.FormFile("cookies")
+	if err != nil {
+		json.NewEncoder(w).Encode(CookiesUploadResponse{Error: "cookies file field required: " + err.Error()})
+		return
+	}
+	defer file.Close()
+
+	if ytdlpBinDir == "" {
+		json.NewEncoder(w).Encode(CookiesUploadResponse{Error: "no managed data dir configured"})
+		return
+	}
+	if err := os.MkdirAll(ytdlpBinDir, 0755); err != nil {
+		json.NewEncoder(w).Encode(CookiesUploadResponse{Error: "mkdir: " + err.Error()})
+		return
+	}
+
+	dest := filepath.Join(ytdlpBinDir, "cookies.txt")
+	out, err := os.Create(dest)
+	if err != nil {
+		json.NewEncoder(w).Encode(CookiesUploadResponse{Error: "create: " + err.Error()})
+		return
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, file); err != nil {
+		json.NewEncoder(w).Encode(CookiesUploadResponse{Error: "write: " + err.Error()})
+		return
+	}
+
+	absDest, _ := filepath.Abs(dest)
+	log.Printf("[cookies] saved uploaded cookies.txt to %s", absDest)
+	json.NewEncoder(w).Encode(CookiesUploadResponse{Path: absDest})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// addressPool hands out local source addresses round-robin (least-recently
+// used first) for --source-address, and temporarily benches one that just hit
+// a 429/403 so the next job picks a different egress IP. Mirrors the
+// ip_manager pattern some ecosystem yt-dlp wrappers use to spread bulk
+// playlist downloads across several IPs.
+type addressPool struct {
+	mu       sync.Mutex
+	addrs    []string
+	lastUsed map[string]time.Time
+	benched  map[string]time.Time
+}
+
+func newAddressPool(addrs []string) *addressPool {
+	p := &addressPool{
+		addrs:    addrs,
+		lastUsed: make(map[string]time.Time, len(addrs)),
+		benched:  make(map[string]time.Time, len(addrs)),
+	}
+	for _, a := range addrs {
+		p.lastUsed[a] = time.Time{}
+	}
+	return p
+}
+
+// acquire returns the least-recently-used non-benched address, or "" if the
+// pool is empty (meaning: don't bind --source-address at all).
+func (p *addressPool) acquire() string {
+	if p == nil || len(p.addrs) == 0 {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	best := ""
+	var bestLast time.Time
+	for _, a := range p.addrs {
+		if until, ok := p.benched[a]; ok && now.Before(until) {
+			continue
+		}
+		last := p.lastUsed[a]
+		if best == "" || last.Before(bestLast) {
+			best = a
+			bestLast = last
+		}
+	}
+	if best == "" {
+		// everything benched — pick the one coming off backoff soonest
+		for _, a := range p.addrs {
+			if best == "" || p.benched[a].Before(p.benched[best]) {
+				best = a
+			}
+		}
+	}
+	p.lastUsed[best] = now
+	return best
+}
+
+// release marks addr as used just now, and benches it for a backoff window
+// if the caller observed a 429/403 on it.
+func (p *addressPool) release(addr string, rateLimited bool) {
+	if p == nil || addr == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastUsed[addr] = time.Now()
+	if rateLimited {
+		p.benched[addr] = time.Now().Add(5 * time.Minute)
+	}
+}
+
+// looksRateLimited reports whether stderr/err indicate YouTube rejected the
+// request with a 429 or 403, the two statuses worth rotating IPs over.
+func looksRateLimited(stderr string) bool {
+	return strings.Contains(stderr, "HTTP Error 429") || strings.Contains(stderr, "HTTP Error 403")
+}
+
+// DownloadYouTubePlaylistConcurrent enumerates every video ID in a playlist
+// with a single `--flat-playlist --print id` call, then fans the individual
+// video downloads out across a bounded worker pool (req.Concurrency workers,
+// default min(4, NumCPU)), optionally round-robining egress IPs from
+// req.SourceAddresses. Results and per-video errors are aggregated into the
+// same DownloadResponse shape the sequential path returns.
+func DownloadYouTubePlaylistConcurrent(req DownloadRequest, outputDir string, events chan<- DownloadEvent) ([]DownloadedFile, []string, error) {
+	ids, err := listPlaylistVideoIDs(req.URL, req.MaxTracks, req.CookiesFile, req.CookiesFromBrowser)
+	if err != nil {
+		return nil, nil, fmt.Errorf("enumerate playlist: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("no videos found in playlist")
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+		if concurrency > 4 {
+			concurrency = 4
+		}
+	}
+
+	pool := newAddressPool(req.SourceAddresses)
+
+	type jobResult struct {
+		files []DownloadedFile
+		err   error
+	}
+	results := make([]jobResult, len(ids))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(idx int, videoID string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			addr := pool.acquire()
+			videoURL := "https://www.youtube.com/watch?v=" + videoID
+			files, dlErr := downloadYouTube(videoURL, outputDir, 0, req.CookiesFile, req.CookiesFromBrowser, addr, events)
+			rateLimited := dlErr != nil && looksRateLimited(dlErr.Error())
+			pool.release(addr, rateLimited)
+			results[idx] = jobResult{files: files, err: dlErr}
+		}(i, id)
+	}
+	wg.Wait()
+
+	var files []DownloadedFile
+	var errs []string
+	for i, r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ids[i], r.err))
+			continue
+		}
+		files = append(files, r.files...)
+	}
+	return files, errs, nil
+}
+
+// listPlaylistVideoIDs runs `yt-dlp --flat-playlist --print id` to enumerate
+// a playlist's video IDs without downloading anything.
+func listPlaylistVideoIDs(url string, maxTracks int, cookiesFile, cookiesFromBrowser string) ([]string, error) {
+	if getYtdlpPath() == "" {
+		return nil, fmt.Errorf("yt-dlp is not available")
+	}
+
+	args := []string{url, "--flat-playlist", "--print", "id", "--no-warnings"}
+	if maxTracks > 0 {
+		args = append(args, "--playlist-end", fmt.Sprintf("%d", maxTracks))
+	}
+	if cookiesFile != "" {
+		args = append(args, "--cookies", cookiesFile)
+	} else if cookiesFromBrowser != "" {
+		args = append(args, "--cookies-from-browser", cookiesFromBrowser)
+	}
+
+	cmd := exec.Command(getYtdlpPath(), args...)
+	hideWindow(cmd)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp --flat-playlist: %w\n%s", err, stderr.String())
+	}
+
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			ids = append(ids, line)
+		}
+	}
+	log.Printf("[yt-dlp] enumerated %d video(s) for concurrent download", len(ids))
+	return ids, nil
+}
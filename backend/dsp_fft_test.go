@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// naiveAutocorr computes the same onset-envelope autocorrelation
+// estimateBPM needs, via the direct O(N*maxLag) sum it used before the FFT
+// rewrite, to cross-check fft/ifft's Wiener-Khinchin result at each lag.
+func naiveAutocorr(onset []float64, lag int) float64 {
+	sum := 0.0
+	for i := 0; i+lag < len(onset); i++ {
+		sum += onset[i] * onset[i+lag]
+	}
+	return sum
+}
+
+// fftAutocorr mirrors estimateBPM's FFT-based autocorrelation: zero-pad to
+// 2x the signal length, take the power spectrum, and inverse-transform it.
+func fftAutocorr(onset []float64) []float64 {
+	fftSize := nextPow2(2 * len(onset))
+	padded := make([]complex128, fftSize)
+	for i, v := range onset {
+		padded[i] = complex(v, 0)
+	}
+	spec := fft(padded)
+	for i, v := range spec {
+		spec[i] = v * cmplx.Conj(v)
+	}
+	autocorr := ifft(spec)
+	out := make([]float64, fftSize)
+	for i, v := range autocorr {
+		out[i] = real(v)
+	}
+	return out
+}
+
+func TestFFTAutocorrMatchesNaiveSum(t *testing.T) {
+	onset := make([]float64, 200)
+	for i := range onset {
+		onset[i] = math.Sin(2*math.Pi*float64(i)/17.0) + 0.3*math.Sin(2*math.Pi*float64(i)/5.0)
+	}
+
+	got := fftAutocorr(onset)
+
+	const tol = 1e-6
+	for lag := 0; lag < 40; lag++ {
+		want := naiveAutocorr(onset, lag)
+		if diff := math.Abs(got[lag] - want); diff > tol {
+			t.Fatalf("lag %d: fft autocorr = %g, naive = %g (diff %g)", lag, got[lag], want, diff)
+		}
+	}
+}
+
+func TestIFFTInvertsFFT(t *testing.T) {
+	in := make([]complex128, 64)
+	for i := range in {
+		in[i] = complex(math.Sin(float64(i)), math.Cos(float64(i)*0.5))
+	}
+
+	out := ifft(fft(in))
+
+	const tol = 1e-9
+	for i := range in {
+		if cmplx.Abs(out[i]-in[i]) > tol {
+			t.Fatalf("index %d: ifft(fft(x)) = %v, want %v", i, out[i], in[i])
+		}
+	}
+}
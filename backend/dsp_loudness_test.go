@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// sine generates a full-cycle sine tone at freq Hz, dur seconds long, at sr
+// samples/sec — the standard EBU R128 calibration signal shape (a steady
+// sine run long enough to fill several 400ms measurement blocks).
+func sine(freq, amplitude, dur float64, sr int) []float32 {
+	n := int(dur * float64(sr))
+	out := make([]float32, n)
+	for i := range out {
+		t := float64(i) / float64(sr)
+		out[i] = float32(amplitude * math.Sin(2*math.Pi*freq*t))
+	}
+	return out
+}
+
+// TestComputeLoudnessLUFSReferenceSine checks computeLoudnessLUFS against a
+// known reference value for a full-scale 997Hz sine (EBU Tech 3341's
+// standard calibration tone, chosen away from both the K-filter's 1681Hz
+// shelf and 38Hz high-pass so its measured loudness is governed almost
+// entirely by the -0.691 + 10*log10(meanSquare) formula): a full-scale sine
+// has mean-square 0.5, so it should land close to -0.691 + 10*log10(0.5) =
+// -3.70 LUFS, with the small remaining gap from the K-filter's own response
+// at 997Hz.
+func TestComputeLoudnessLUFSReferenceSine(t *testing.T) {
+	sr := 48000
+	samples := sine(997.0, 1.0, 3.0, sr)
+
+	got := computeLoudnessLUFS(samples, sr)
+	const want = -3.70
+	const tol = 0.6
+	if math.Abs(got-want) > tol {
+		t.Fatalf("997Hz full-scale sine: got %.3f LUFS, want %.3f +/- %.1f", got, want, tol)
+	}
+}
+
+// TestComputeLoudnessLUFSHalvesWithAmplitude checks that halving a sine's
+// amplitude reduces measured loudness by ~6.02dB (20*log10(0.5)), the basic
+// relationship any correct loudness measure has to preserve.
+func TestComputeLoudnessLUFSHalvesWithAmplitude(t *testing.T) {
+	sr := 48000
+	full := computeLoudnessLUFS(sine(997.0, 1.0, 3.0, sr), sr)
+	half := computeLoudnessLUFS(sine(997.0, 0.5, 3.0, sr), sr)
+
+	delta := full - half
+	const want = 6.02
+	const tol = 0.2
+	if math.Abs(delta-want) > tol {
+		t.Fatalf("full-vs-half amplitude delta = %.3f dB, want %.3f +/- %.1f", delta, want, tol)
+	}
+}
+
+// TestComputeLoudnessLUFSSilenceHitsAbsoluteGate checks that silence (no
+// blocks above the -70 LUFS absolute gate) reports the gate floor rather
+// than a spuriously high or NaN value.
+func TestComputeLoudnessLUFSSilenceHitsAbsoluteGate(t *testing.T) {
+	sr := 48000
+	silence := make([]float32, int(3.0*float64(sr)))
+	got := computeLoudnessLUFS(silence, sr)
+	if got != r128AbsoluteGateLUFS {
+		t.Fatalf("silence: got %.3f LUFS, want the absolute gate floor %.3f", got, r128AbsoluteGateLUFS)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/vividhyeok/djbot/backend/audio"
+)
+
+// Filter is one stage of a configurable DSP chain: it consumes a mono
+// float32 buffer at a given sample rate and returns the processed buffer,
+// possibly at a different sample rate (only ResamplerFilter actually
+// changes it).
+type Filter interface {
+	Process(samples []float32, sr int) ([]float32, int, error)
+}
+
+// FilterSpec describes one chain stage in the JSON shape a render/export
+// request carries: a type name plus a small set of named parameters. See
+// newFilterInstance for the params each type understands.
+type FilterSpec struct {
+	Type   string             `json:"type"`
+	Params map[string]float64 `json:"params,omitempty"`
+}
+
+// filterPresets are the built-in chains RenderMixRequest.FilterPreset can
+// select without the caller spelling out every stage.
+var filterPresets = map[string][]FilterSpec{
+	"club-master": {
+		{Type: "highpass", Params: map[string]float64{"freq": 30, "q": 0.707}},
+		{Type: "compressor", Params: map[string]float64{"threshold_db": -18, "ratio": 2, "attack_ms": 10, "release_ms": 100, "makeup_db": 2}},
+		{Type: "limiter", Params: map[string]float64{"ceiling_db": -1}},
+	},
+	"podcast": {
+		{Type: "highpass", Params: map[string]float64{"freq": 80, "q": 0.707}},
+		{Type: "parametric_eq", Params: map[string]float64{"freq": 3000, "q": 1.0, "gain_db": 3}},
+		{Type: "compressor", Params: map[string]float64{"threshold_db": -20, "ratio": 4, "attack_ms": 5, "release_ms": 150, "makeup_db": 4}},
+		{Type: "limiter", Params: map[string]float64{"ceiling_db": -1}},
+	},
+}
+
+// ResolveFilterChainSpecs picks custom when given (a caller-specified chain
+// always wins), otherwise looks up presetName, otherwise returns nil (no
+// chain — render stays a straight passthrough, the prior behavior).
+func ResolveFilterChainSpecs(presetName string, custom []FilterSpec) []FilterSpec {
+	if len(custom) > 0 {
+		return custom
+	}
+	return filterPresets[presetName]
+}
+
+func paramOrDefault(params map[string]float64, key string, def float64) float64 {
+	if v, ok := params[key]; ok {
+		return v
+	}
+	return def
+}
+
+// newFilterInstance builds a fresh Filter for spec. Fresh per call
+// deliberately — biquad-based filters carry per-channel running state, so
+// ApplyFilterChainStereo builds one instance per channel rather than
+// sharing a single instance across both.
+func newFilterInstance(spec FilterSpec) (Filter, error) {
+	switch spec.Type {
+	case "highpass":
+		return &biquadFilter{kind: HighPass, freq: paramOrDefault(spec.Params, "freq", 80), q: paramOrDefault(spec.Params, "q", 0.707)}, nil
+	case "lowpass":
+		return &biquadFilter{kind: LowPass, freq: paramOrDefault(spec.Params, "freq", 18000), q: paramOrDefault(spec.Params, "q", 0.707)}, nil
+	case "parametric_eq":
+		return &biquadFilter{kind: Peaking, freq: paramOrDefault(spec.Params, "freq", 1000), q: paramOrDefault(spec.Params, "q", 1.0), gainDB: paramOrDefault(spec.Params, "gain_db", 0)}, nil
+	case "compressor":
+		return &compressorFilter{
+			thresholdDB: paramOrDefault(spec.Params, "threshold_db", -18),
+			ratio:       paramOrDefault(spec.Params, "ratio", 2),
+			attackMs:    paramOrDefault(spec.Params, "attack_ms", 10),
+			releaseMs:   paramOrDefault(spec.Params, "release_ms", 100),
+			makeupDB:    paramOrDefault(spec.Params, "makeup_db", 0),
+		}, nil
+	case "limiter":
+		return &limiterFilter{ceilingDB: paramOrDefault(spec.Params, "ceiling_db", -1)}, nil
+	case "resample":
+		return &resamplerFilter{targetSR: int(paramOrDefault(spec.Params, "target_sr", 44100))}, nil
+	case "replaygain":
+		return &replayGainFilter{gainDB: paramOrDefault(spec.Params, "gain_db", 0)}, nil
+	default:
+		return nil, fmt.Errorf("filterchain: unknown filter type %q", spec.Type)
+	}
+}
+
+// biquadFilter wraps a Biquad (see biquad.go) for the highpass/lowpass/
+// parametric_eq chain stages.
+type biquadFilter struct {
+	kind            Kind
+	freq, q, gainDB float64
+}
+
+func (f *biquadFilter) Process(samples []float32, sr int) ([]float32, int, error) {
+	bq := NewBiquad(sr, f.kind, f.freq, f.q, f.gainDB)
+	out := make([]float32, len(samples))
+	bq.Process(samples, out)
+	return out, sr, nil
+}
+
+// compressorFilter is a feedforward RMS-style compressor: an exponential
+// envelope follower drives gain reduction above thresholdDB at 1/ratio,
+// plus a flat makeup gain.
+type compressorFilter struct {
+	thresholdDB, ratio, attackMs, releaseMs, makeupDB float64
+}
+
+func (f *compressorFilter) Process(samples []float32, sr int) ([]float32, int, error) {
+	attackCoef := math.Exp(-1.0 / (float64(sr) * f.attackMs / 1000.0))
+	releaseCoef := math.Exp(-1.0 / (float64(sr) * f.releaseMs / 1000.0))
+
+	out := make([]float32, len(samples))
+	envelope := 0.0
+	for i, s := range samples {
+		input := math.Abs(float64(s))
+		if input > envelope {
+			envelope = attackCoef*envelope + (1-attackCoef)*input
+		} else {
+			envelope = releaseCoef*envelope + (1-releaseCoef)*input
+		}
+
+		envDB := 20 * math.Log10(envelope+1e-9)
+		gainReductionDB := 0.0
+		if envDB > f.thresholdDB {
+			gainReductionDB = (envDB - f.thresholdDB) * (1 - 1/f.ratio)
+		}
+		gain := math.Pow(10, (f.makeupDB-gainReductionDB)/20)
+		out[i] = float32(float64(s) * gain)
+	}
+	return out, sr, nil
+}
+
+// limiterFilter is a fast-attack, 50ms-release brickwall-style limiter: the
+// envelope follower tracks peaks instantly (no attack smoothing) so nothing
+// crosses ceilingDB, and releases gradually to avoid audible pumping.
+type limiterFilter struct {
+	ceilingDB float64
+}
+
+func (f *limiterFilter) Process(samples []float32, sr int) ([]float32, int, error) {
+	ceiling := math.Pow(10, f.ceilingDB/20)
+	const releaseSeconds = 0.050
+	releaseCoef := math.Exp(-1.0 / (float64(sr) * releaseSeconds))
+
+	out := make([]float32, len(samples))
+	envelope := 0.0
+	for i, s := range samples {
+		input := math.Abs(float64(s))
+		if input > envelope {
+			envelope = input
+		} else {
+			envelope = releaseCoef*envelope + (1-releaseCoef)*input
+		}
+		gain := 1.0
+		if envelope > ceiling {
+			gain = ceiling / envelope
+		}
+		out[i] = float32(float64(s) * gain)
+	}
+	return out, sr, nil
+}
+
+// resamplerFilter wraps audio.Resample (the shared polyphase-FIR resampler
+// already used by fingerprinting and true-peak detection) so a render can
+// target 44.1kHz or 48kHz regardless of source rate.
+type resamplerFilter struct {
+	targetSR int
+}
+
+func (f *resamplerFilter) Process(samples []float32, sr int) ([]float32, int, error) {
+	if f.targetSR <= 0 || f.targetSR == sr {
+		return samples, sr, nil
+	}
+	return audio.Resample(samples, sr, f.targetSR), f.targetSR, nil
+}
+
+// replayGainFilter applies a flat dB gain, e.g. a track's stored
+// ReplayGainTrackGain tag.
+type replayGainFilter struct {
+	gainDB float64
+}
+
+func (f *replayGainFilter) Process(samples []float32, sr int) ([]float32, int, error) {
+	gain := float32(math.Pow(10, f.gainDB/20))
+	out := make([]float32, len(samples))
+	for i, s := range samples {
+		out[i] = s * gain
+	}
+	return out, sr, nil
+}
+
+// ApplyFilterChainStereo runs specs over an interleaved stereo buffer,
+// processing each channel through its own instance of every stage (biquad
+// and envelope-follower state is per-channel, never shared) and
+// re-interleaving the result. Returns the buffer unchanged when specs is
+// empty.
+func ApplyFilterChainStereo(interleaved []float32, sr int, specs []FilterSpec) ([]float32, int, error) {
+	if len(specs) == 0 {
+		return interleaved, sr, nil
+	}
+
+	left, right := deinterleaveStereo(interleaved)
+	curSR := sr
+
+	for _, spec := range specs {
+		leftFilter, err := newFilterInstance(spec)
+		if err != nil {
+			return nil, 0, err
+		}
+		rightFilter, err := newFilterInstance(spec)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var newSR int
+		left, newSR, err = leftFilter.Process(left, curSR)
+		if err != nil {
+			return nil, 0, fmt.Errorf("filterchain: %s: %w", spec.Type, err)
+		}
+		right, _, err = rightFilter.Process(right, curSR)
+		if err != nil {
+			return nil, 0, fmt.Errorf("filterchain: %s: %w", spec.Type, err)
+		}
+		curSR = newSR
+	}
+
+	return interleaveStereo(left, right), curSR, nil
+}
+
+func deinterleaveStereo(interleaved []float32) (left, right []float32) {
+	n := len(interleaved) / 2
+	left = make([]float32, n)
+	right = make([]float32, n)
+	for i := 0; i < n; i++ {
+		left[i] = interleaved[i*2]
+		right[i] = interleaved[i*2+1]
+	}
+	return left, right
+}
+
+func interleaveStereo(left, right []float32) []float32 {
+	n := len(left)
+	if len(right) < n {
+		n = len(right)
+	}
+	out := make([]float32, n*2)
+	for i := 0; i < n; i++ {
+		out[i*2] = left[i]
+		out[i*2+1] = right[i]
+	}
+	return out
+}
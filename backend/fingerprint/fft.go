@@ -0,0 +1,53 @@
+package fingerprint
+
+import "math"
+
+// fft is an iterative radix-2 Cooley-Tukey transform; callers always pass a
+// power-of-two length (FrameSize), so it has no padding step. Like dsp.go's
+// fft in the main package, which this mirrors, it leaves padding to callers.
+func fft(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	copy(out, x)
+	if n <= 1 {
+		return out
+	}
+
+	j := 0
+	for i := 0; i < n-1; i++ {
+		if i < j {
+			out[i], out[j] = out[j], out[i]
+		}
+		m := n >> 1
+		for j >= m && m > 0 {
+			j -= m
+			m >>= 1
+		}
+		j += m
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size >> 1
+		step := -2 * math.Pi / float64(size)
+		wLen := complex(math.Cos(step), math.Sin(step))
+		for i := 0; i < n; i += size {
+			w := complex(1, 0)
+			for k := 0; k < half; k++ {
+				u := out[i+k]
+				v := out[i+k+half] * w
+				out[i+k] = u + v
+				out[i+k+half] = u - v
+				w *= wLen
+			}
+		}
+	}
+	return out
+}
+
+func hannWindow(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(n-1)))
+	}
+	return w
+}
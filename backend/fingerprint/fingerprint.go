@@ -0,0 +1,124 @@
+// Package fingerprint computes a Chromaprint-style acoustic fingerprint
+// over raw PCM: a 12-bin chroma STFT reduced to one 16-bit hash per frame,
+// median-filtered across time. It's meant for coarse "is this the same
+// recording" comparisons — mix dedupe, AcoustID lookups — not for the
+// pitch/tempo-tolerant track-internal matching backend's own
+// ComputeFingerprints (Panako-style) already does; the two solve different
+// problems and intentionally don't share an algorithm.
+package fingerprint
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+const (
+	// FrameSize/HopSize are the STFT window and hop, in samples at the
+	// caller's sample rate.
+	FrameSize = 4096
+	HopSize   = 2048
+
+	numChromaBins = 12
+	medianRadius  = 2 // 5-wide median filter (radius either side) over the hash stream
+)
+
+// Fingerprint is the result of Compute: one 16-bit hash per analysis frame.
+type Fingerprint struct {
+	Hashes []uint16 `json:"hashes"`
+}
+
+// Compute runs the full pipeline over mono PCM samples at sampleRate — a
+// 4096-sample/2048-hop STFT, a 12-bin chroma (pitch-class) projection per
+// frame packed into a 16-bit hash, then a median filter across the
+// resulting hash stream to damp single-frame noise.
+func Compute(samples []float32, sampleRate int) (Fingerprint, error) {
+	if sampleRate <= 0 {
+		return Fingerprint{}, fmt.Errorf("fingerprint: sampleRate must be positive, got %d", sampleRate)
+	}
+	if len(samples) < FrameSize {
+		return Fingerprint{}, nil
+	}
+
+	window := hannWindow(FrameSize)
+	numFrames := (len(samples)-FrameSize)/HopSize + 1
+	frame := make([]complex128, FrameSize)
+	raw := make([]uint16, numFrames)
+
+	for i := 0; i < numFrames; i++ {
+		start := i * HopSize
+		for j := 0; j < FrameSize; j++ {
+			frame[j] = complex(float64(samples[start+j])*window[j], 0)
+		}
+		spec := fft(frame)
+		raw[i] = packChromaHash(chromaVector(spec, sampleRate))
+	}
+
+	return Fingerprint{Hashes: medianFilter(raw, medianRadius)}, nil
+}
+
+// chromaVector folds the positive half of spec's spectrum into 12 pitch
+// classes (MIDI note mod 12, referenced to A440) by summing magnitude —
+// the standard chroma/pitch-class-profile reduction.
+func chromaVector(spec []complex128, sampleRate int) [numChromaBins]float64 {
+	var chroma [numChromaBins]float64
+	n := len(spec)
+	for k := 1; k < n/2; k++ { // skip DC
+		freq := float64(k) * float64(sampleRate) / float64(n)
+		if freq < 20 {
+			continue
+		}
+		midi := 69.0 + 12.0*math.Log2(freq/440.0)
+		class := int(math.Round(midi)) % numChromaBins
+		if class < 0 {
+			class += numChromaBins
+		}
+		chroma[class] += cmplx.Abs(spec[k])
+	}
+	return chroma
+}
+
+// packChromaHash packs a chroma frame into 16 bits: bits 0-11 are the sign
+// of each adjacent circular pair (c[i] > c[i+1]), mirroring Chromaprint's
+// own adjacent-band-comparison filters, and bits 12-15 are the index of the
+// frame's dominant (loudest) pitch class, giving the hash some absolute
+// signal alongside the relative comparisons.
+func packChromaHash(c [numChromaBins]float64) uint16 {
+	var hash uint16
+	for i := 0; i < numChromaBins; i++ {
+		next := (i + 1) % numChromaBins
+		if c[i] > c[next] {
+			hash |= 1 << uint(i)
+		}
+	}
+	maxIdx := 0
+	for i := 1; i < numChromaBins; i++ {
+		if c[i] > c[maxIdx] {
+			maxIdx = i
+		}
+	}
+	hash |= uint16(maxIdx) << 12
+	return hash
+}
+
+// medianFilter replaces each value with the median of itself and its
+// radius neighbors on either side, smoothing over single-frame hash flips
+// caused by STFT leakage near a chroma boundary.
+func medianFilter(values []uint16, radius int) []uint16 {
+	out := make([]uint16, len(values))
+	window := make([]uint16, 0, 2*radius+1)
+	for i := range values {
+		window = window[:0]
+		for d := -radius; d <= radius; d++ {
+			j := i + d
+			if j < 0 || j >= len(values) {
+				continue
+			}
+			window = append(window, values[j])
+		}
+		sort.Slice(window, func(a, b int) bool { return window[a] < window[b] })
+		out[i] = window[len(window)/2]
+	}
+	return out
+}
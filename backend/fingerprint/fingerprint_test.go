@@ -0,0 +1,83 @@
+package fingerprint
+
+import (
+	"math"
+	"testing"
+)
+
+// sineSweep generates a short linear chirp from startHz to endHz, the
+// standard "known, reproducible signal" test input for a fingerprinter.
+func sineSweep(durationSec float64, startHz, endHz float64, sampleRate int) []float32 {
+	n := int(durationSec * float64(sampleRate))
+	out := make([]float32, n)
+	for i := range out {
+		t := float64(i) / float64(sampleRate)
+		phase := 2 * math.Pi * (startHz*t + (endHz-startHz)*t*t/(2*durationSec))
+		out[i] = float32(math.Sin(phase))
+	}
+	return out
+}
+
+func TestComputeIsDeterministic(t *testing.T) {
+	samples := sineSweep(3.0, 220.0, 880.0, 44100)
+
+	fp1, err := Compute(samples, 44100)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	fp2, err := Compute(samples, 44100)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+
+	if len(fp1.Hashes) == 0 {
+		t.Fatal("expected at least one hash for a 3s sweep")
+	}
+	if len(fp1.Hashes) != len(fp2.Hashes) {
+		t.Fatalf("hash count not deterministic: %d vs %d", len(fp1.Hashes), len(fp2.Hashes))
+	}
+	for i := range fp1.Hashes {
+		if fp1.Hashes[i] != fp2.Hashes[i] {
+			t.Fatalf("hash[%d] not deterministic: %d vs %d", i, fp1.Hashes[i], fp2.Hashes[i])
+		}
+	}
+
+	wantFrames := (len(samples)-FrameSize)/HopSize + 1
+	if len(fp1.Hashes) != wantFrames {
+		t.Fatalf("expected %d frames, got %d", wantFrames, len(fp1.Hashes))
+	}
+}
+
+func TestComputeDistinguishesDifferentSignals(t *testing.T) {
+	low := sineSweep(3.0, 110.0, 110.0, 44100)
+	high := sineSweep(3.0, 880.0, 880.0, 44100)
+
+	fpLow, err := Compute(low, 44100)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	fpHigh, err := Compute(high, 44100)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+
+	same := 0
+	for i := range fpLow.Hashes {
+		if i < len(fpHigh.Hashes) && fpLow.Hashes[i] == fpHigh.Hashes[i] {
+			same++
+		}
+	}
+	if same == len(fpLow.Hashes) {
+		t.Fatal("expected a 110Hz tone and an 880Hz tone to produce different hashes")
+	}
+}
+
+func TestComputeTooShort(t *testing.T) {
+	fp, err := Compute(make([]float32, FrameSize-1), 44100)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	if len(fp.Hashes) != 0 {
+		t.Fatalf("expected no hashes for input shorter than one frame, got %d", len(fp.Hashes))
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// fmp4TimeScale is the media timescale fMP4 segments use for this stream:
+// 44.1kHz matches canvasSR in the common (no resample-filter) case, so
+// sample counts map 1:1 to timescale units without a conversion.
+const fmp4TimeScale = 44100
+
+// box wraps payload in a standard ISO-BMFF box: a 4-byte big-endian size
+// (including the 8-byte header) followed by the 4-byte ASCII type.
+func box(boxType string, payload []byte) []byte {
+	if len(boxType) != 4 {
+		panic(fmt.Sprintf("fmp4: box type must be 4 chars, got %q", boxType))
+	}
+	buf := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	copy(buf[4:8], boxType)
+	copy(buf[8:], payload)
+	return buf
+}
+
+// fullBox is a box whose payload starts with the usual 1-byte version + 3-byte flags header.
+func fullBox(boxType string, version byte, flags uint32, rest []byte) []byte {
+	payload := make([]byte, 4+len(rest))
+	payload[0] = version
+	payload[1] = byte(flags >> 16)
+	payload[2] = byte(flags >> 8)
+	payload[3] = byte(flags)
+	copy(payload[4:], rest)
+	return box(boxType, payload)
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func concatBoxes(boxes ...[]byte) []byte {
+	var out []byte
+	for _, b := range boxes {
+		out = append(out, b...)
+	}
+	return out
+}
+
+// aacAudioSpecificConfig builds the 2-byte AudioSpecificConfig mp4a's esds
+// box needs: AAC-LC (object type 2), sampleRate/channels as given, matching
+// the ffmpeg -c:a aac encoder's own default config for those parameters.
+func aacAudioSpecificConfig(sampleRate, channels int) []byte {
+	freqIdx := map[int]byte{
+		96000: 0, 88200: 1, 64000: 2, 48000: 3, 44100: 4,
+		32000: 5, 24000: 6, 22050: 7, 16000: 8, 12000: 9, 11025: 10, 8000: 11,
+	}[sampleRate]
+	objType := byte(2) // AAC-LC
+	b0 := (objType << 3) | (freqIdx >> 1)
+	b1 := (freqIdx << 7) | (byte(channels) << 3)
+	return []byte{b0, b1}
+}
+
+// buildEsds wraps an AudioSpecificConfig in the nested descriptor tags
+// (ES_Descriptor > DecoderConfigDescriptor > DecSpecificInfo) mp4a's esds
+// box needs, using the minimum-viable single-byte length form (our configs
+// are always tiny).
+func buildEsds(asc []byte) []byte {
+	decSpecificInfo := append([]byte{0x05, byte(len(asc))}, asc...)
+
+	decoderConfig := []byte{
+		0x40,       // object type indication: MPEG-4 Audio
+		0x15,       // stream type (audio) << 2 | upstream flag | reserved
+		0, 0, 0,    // buffer size DB (24 bits)
+		0, 1, 0xF4, 0, // max bitrate (placeholder)
+		0, 1, 0xF4, 0, // avg bitrate (placeholder)
+	}
+	decoderConfig = append(decoderConfig, decSpecificInfo...)
+	decoderConfigDesc := append([]byte{0x04, byte(len(decoderConfig))}, decoderConfig...)
+
+	slConfig := []byte{0x06, 0x01, 0x02} // SLConfigDescriptor, predefined=2 (MP4)
+
+	esDescriptor := []byte{0, 0} // ES_ID
+	esDescriptor = append(esDescriptor, 0x00)
+	esDescriptor = append(esDescriptor, decoderConfigDesc...)
+	esDescriptor = append(esDescriptor, slConfig...)
+	esDescriptorTagged := append([]byte{0x03, byte(len(esDescriptor))}, esDescriptor...)
+
+	return fullBox("esds", 0, 0, esDescriptorTagged)
+}
+
+// buildMP4A builds the sample entry describing one AAC audio track.
+func buildMP4A(sampleRate, channels int) []byte {
+	reserved := make([]byte, 6)
+	payload := append([]byte{}, reserved...)
+	payload = append(payload, u16(1)...) // data reference index
+	payload = append(payload, make([]byte, 8)...)
+	payload = append(payload, u16(uint16(channels))...)
+	payload = append(payload, u16(16)...) // sample size bits
+	payload = append(payload, make([]byte, 4)...)
+	payload = append(payload, u16(uint16(sampleRate))...)
+	payload = append(payload, u16(0)...) // fixed-point fraction
+	payload = append(payload, buildEsds(aacAudioSpecificConfig(sampleRate, channels))...)
+	return box("mp4a", payload)
+}
+
+func buildStsd(sampleRate, channels int) []byte {
+	payload := append(u32(0), u32(1)...) // version/flags, entry count
+	payload = append(payload, buildMP4A(sampleRate, channels)...)
+	return box("stsd", payload)
+}
+
+func emptyTableBox(boxType string) []byte {
+	return fullBox(boxType, 0, 0, u32(0))
+}
+
+func buildStbl(sampleRate, channels int) []byte {
+	return box("stbl", concatBoxes(
+		buildStsd(sampleRate, channels),
+		emptyTableBox("stts"),
+		emptyTableBox("stsc"),
+		fullBox("stsz", 0, 0, append(u32(0), u32(0)...)),
+		emptyTableBox("stco"),
+	))
+}
+
+func buildDinf() []byte {
+	url := fullBox("url ", 0, 1, nil) // flag 1 = "media in same file"
+	dref := fullBox("dref", 0, 0, append(u32(1), url...))
+	return box("dinf", dref)
+}
+
+func buildMinf(sampleRate, channels int) []byte {
+	smhd := fullBox("smhd", 0, 0, make([]byte, 4))
+	return box("minf", concatBoxes(smhd, buildDinf(), buildStbl(sampleRate, channels)))
+}
+
+func buildMdhd(sampleRate int) []byte {
+	rest := concatBoxes(u32(0), u32(0), u32(uint32(sampleRate)), u32(0), u16(0x55C4), u16(0))
+	return fullBox("mdhd", 0, 0, rest)
+}
+
+func buildHdlr() []byte {
+	rest := concatBoxes(u32(0), []byte("soun"), make([]byte, 12), []byte("SoundHandler\x00"))
+	return fullBox("hdlr", 0, 0, rest)
+}
+
+func buildMdia(sampleRate, channels int) []byte {
+	return box("mdia", concatBoxes(buildMdhd(sampleRate), buildHdlr(), buildMinf(sampleRate, channels)))
+}
+
+func buildTkhd(trackID uint32) []byte {
+	rest := concatBoxes(
+		u32(0), u32(0), // creation/modification time
+		u32(trackID), u32(0), // track ID, reserved
+		u32(0),          // duration (0 for fragmented)
+		make([]byte, 8), // reserved
+		u16(0), u16(0),  // layer, alternate group
+		u16(0x0100), u16(0), // volume, reserved
+		// unity matrix
+		u32(0x00010000), u32(0), u32(0),
+		u32(0), u32(0x00010000), u32(0),
+		u32(0), u32(0), u32(0x40000000),
+		u32(0), u32(0), // width/height (audio-only track)
+	)
+	return fullBox("tkhd", 0, 1, rest) // flag 1 = track enabled
+}
+
+func buildTrak(trackID uint32, sampleRate, channels int) []byte {
+	return box("trak", concatBoxes(buildTkhd(trackID), buildMdia(sampleRate, channels)))
+}
+
+func buildMvhd(nextTrackID uint32) []byte {
+	rest := concatBoxes(
+		u32(0), u32(0), // creation/modification time
+		u32(fmp4TimeScale), u32(0), // timescale, duration
+		u32(0x00010000), u16(0x0100), u16(0), // rate, volume, reserved
+		u32(0), u32(0), // reserved
+		u32(0x00010000), u32(0), u32(0),
+		u32(0), u32(0x00010000), u32(0),
+		u32(0), u32(0), u32(0x40000000),
+		make([]byte, 24), // pre_defined
+		u32(nextTrackID),
+	)
+	return fullBox("mvhd", 0, 0, rest)
+}
+
+func buildMvex(trackID uint32) []byte {
+	trex := fullBox("trex", 0, 0, concatBoxes(u32(trackID), u32(1), u32(0), u32(0), u32(0)))
+	return box("mvex", trex)
+}
+
+// buildInitSegment produces the ftyp+moov pair a fragmented-MP4 player needs
+// before it can play any moof/mdat segment: one AAC-LC audio track,
+// described once here and never repeated in the per-segment data.
+func buildInitSegment(sampleRate, channels int) []byte {
+	const trackID = 1
+	ftyp := box("ftyp", concatBoxes([]byte("iso5"), u32(0), []byte("iso5"), []byte("iso6"), []byte("mp41")))
+	moov := box("moov", concatBoxes(
+		buildMvhd(trackID+1),
+		buildTrak(trackID, sampleRate, channels),
+		buildMvex(trackID),
+	))
+	return concatBoxes(ftyp, moov)
+}
+
+// aacFrame is one ADTS frame's AAC payload (header stripped) plus its
+// duration in samples (1024 for every AAC-LC frame ffmpeg produces here).
+type aacFrame struct {
+	payload       []byte
+	samplesPerAAC uint32
+}
+
+// parseADTSFrames splits an ADTS bitstream (ffmpeg's `-f adts` output) into
+// its individual frames, stripping each 7-byte (or 9-byte with CRC) ADTS
+// header so only the raw AAC payload remains for the mdat box.
+func parseADTSFrames(adts []byte) ([]aacFrame, error) {
+	var frames []aacFrame
+	pos := 0
+	for pos+7 <= len(adts) {
+		if adts[pos] != 0xFF || adts[pos+1]&0xF0 != 0xF0 {
+			return nil, fmt.Errorf("fmp4: bad ADTS sync at byte %d", pos)
+		}
+		hasCRC := adts[pos+1]&0x01 == 0
+		frameLen := int(adts[pos+3]&0x03)<<11 | int(adts[pos+4])<<3 | int(adts[pos+5])>>5
+		headerLen := 7
+		if hasCRC {
+			headerLen = 9
+		}
+		if frameLen < headerLen || pos+frameLen > len(adts) {
+			return nil, fmt.Errorf("fmp4: truncated ADTS frame at byte %d", pos)
+		}
+		frames = append(frames, aacFrame{
+			payload:       adts[pos+headerLen : pos+frameLen],
+			samplesPerAAC: 1024,
+		})
+		pos += frameLen
+	}
+	return frames, nil
+}
+
+// buildMediaSegment packs one segment's worth of AAC frames into a
+// moof+mdat pair: a single track fragment run (trun) with per-sample sizes
+// and durations, sample data immediately following in mdat. baseDecodeTime
+// is this segment's starting position in timescale units (running sample
+// count), so players can seek without needing a sidx.
+func buildMediaSegment(seq uint32, frames []aacFrame, baseDecodeTime uint64) []byte {
+	const trackID = 1
+
+	mfhd := fullBox("mfhd", 0, 0, u32(seq))
+
+	var mdatPayload []byte
+	for _, f := range frames {
+		mdatPayload = append(mdatPayload, f.payload...)
+	}
+
+	tfhdFlags := uint32(0x020000) // default-base-is-moof
+	tfhd := fullBox("tfhd", 0, tfhdFlags, u32(trackID))
+	tfdt := fullBox("tfdt", 1, 0, concatBoxes(u32(uint32(baseDecodeTime>>32)), u32(uint32(baseDecodeTime))))
+
+	// trun flags: data-offset-present | sample-duration-present | sample-size-present
+	trunFlags := uint32(0x000001 | 0x000100 | 0x000200)
+	buildTrun := func(dataOffset int32) []byte {
+		rest := concatBoxes(u32(uint32(len(frames))), u32(uint32(dataOffset)))
+		for _, f := range frames {
+			rest = append(rest, u32(f.samplesPerAAC)...)
+			rest = append(rest, u32(uint32(len(f.payload)))...)
+		}
+		return fullBox("trun", 0, trunFlags, rest)
+	}
+
+	// Build once with a placeholder offset to learn moof's size, then
+	// rebuild with the real offset (distance from moof's start to the first
+	// sample byte in the following mdat) now that it's known — simpler and
+	// less error-prone than patching bytes into an already-built box.
+	placeholderMoof := box("moof", concatBoxes(mfhd, box("traf", concatBoxes(tfhd, tfdt, buildTrun(0)))))
+	dataOffset := int32(len(placeholderMoof) + 8) // +8 for mdat's own box header
+	moof := box("moof", concatBoxes(mfhd, box("traf", concatBoxes(tfhd, tfdt, buildTrun(dataOffset)))))
+
+	mdat := box("mdat", mdatPayload)
+	return concatBoxes(moof, mdat)
+}
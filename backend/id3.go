@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// id3TextEncodingUTF8 is the ID3v2.4 text-encoding byte for UTF-8, used for
+// every text-containing frame below since v2.4 (unlike v2.3) supports it
+// directly and it avoids the BOM/UTF-16 dance entirely.
+const id3TextEncodingUTF8 = 0x03
+
+// id3Frame is one encoded ID3v2.4 frame ready to be written: a 4-byte frame
+// ID followed by its already-serialized body.
+type id3Frame struct {
+	ID   string
+	Body []byte
+}
+
+// encodeSynchsafe32 packs n into the 4-byte "synchsafe" integer ID3v2 uses
+// everywhere (tag size, frame size): 7 data bits per byte with the high bit
+// always clear, so a 0xFF byte can never be mistaken for an MPEG sync word.
+func encodeSynchsafe32(n int) [4]byte {
+	var b [4]byte
+	b[0] = byte((n >> 21) & 0x7F)
+	b[1] = byte((n >> 14) & 0x7F)
+	b[2] = byte((n >> 7) & 0x7F)
+	b[3] = byte(n & 0x7F)
+	return b
+}
+
+// writeID3v2Tag serializes frames as an ID3v2.4 tag (10-byte header plus the
+// frames, each with its own 10-byte frame header) and returns the bytes to
+// prepend to an MP3 file.
+func writeID3v2Tag(frames []id3Frame) []byte {
+	var body bytes.Buffer
+	for _, f := range frames {
+		var header [10]byte
+		copy(header[0:4], f.ID)
+		size := encodeSynchsafe32(len(f.Body))
+		copy(header[4:8], size[:])
+		// header[8:10] flags left zero
+		body.Write(header[:])
+		body.Write(f.Body)
+	}
+
+	var tag bytes.Buffer
+	tag.WriteString("ID3")
+	tag.Write([]byte{0x04, 0x00}) // version 2.4.0
+	tag.WriteByte(0x00)           // flags
+	tagSize := encodeSynchsafe32(body.Len())
+	tag.Write(tagSize[:])
+	tag.Write(body.Bytes())
+	return tag.Bytes()
+}
+
+// nullTerminatedUTF8 appends s followed by the single-byte UTF-8 terminator
+// used between the encoding-prefixed fields of most text frames.
+func nullTerminatedUTF8(s string) []byte {
+	return append([]byte(s), 0x00)
+}
+
+// buildTextFrame encodes a plain text frame (TIT2, TPE1, ...): encoding byte
+// followed by the UTF-8 text, unterminated (per spec, trailing text fields
+// are not null-terminated).
+func buildTextFrame(text string) []byte {
+	body := []byte{id3TextEncodingUTF8}
+	return append(body, []byte(text)...)
+}
+
+// buildTXXXFrame builds a user-defined text frame (TXXX), used here to carry
+// BPM/key/gain values that have no dedicated ID3 frame of their own.
+func buildTXXXFrame(description, value string) []byte {
+	body := []byte{id3TextEncodingUTF8}
+	body = append(body, nullTerminatedUTF8(description)...)
+	body = append(body, []byte(value)...)
+	return body
+}
+
+// buildUSLTFrame builds an unsynchronized full-text lyrics frame from the
+// raw LRC content (timestamps and all — USLT has no timing concept, so the
+// bracketed stamps are left inline as most players just show the plain
+// text).
+func buildUSLTFrame(language, lyrics string) []byte {
+	if len(language) != 3 {
+		language = "eng"
+	}
+	body := []byte{id3TextEncodingUTF8}
+	body = append(body, []byte(language)...)
+	body = append(body, nullTerminatedUTF8("")...) // content descriptor
+	body = append(body, []byte(lyrics)...)
+	return body
+}
+
+var lrcLineRe = regexp.MustCompile(`^\[(\d+):(\d+(?:\.\d+)?)\](.*)$`)
+
+// sylLyricLine is one timestamped LRC line, parsed for SYLT embedding.
+type sylLyricLine struct {
+	Millis int
+	Text   string
+}
+
+// parseLRCLines parses `[mm:ss.xx]text` LRC lines into millisecond-stamped
+// entries, skipping metadata lines (e.g. "[ar:...]") that don't match the
+// mm:ss timestamp shape.
+func parseLRCLines(lrc string) []sylLyricLine {
+	var out []sylLyricLine
+	for _, raw := range strings.Split(lrc, "\n") {
+		line := strings.TrimRight(raw, "\r")
+		m := lrcLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		minutes, _ := strconv.Atoi(m[1])
+		seconds, _ := strconv.ParseFloat(m[2], 64)
+		text := strings.TrimSpace(m[3])
+		out = append(out, sylLyricLine{
+			Millis: minutes*60000 + int(seconds*1000),
+			Text:   text,
+		})
+	}
+	return out
+}
+
+// buildSYLTFrame builds a synchronized lyrics frame from LRC content: one
+// (text, millisecond-timestamp) pair per parsed line, so players that
+// support SYLT can highlight lyrics in time with playback.
+func buildSYLTFrame(language, lrc string) []byte {
+	if len(language) != 3 {
+		language = "eng"
+	}
+	lines := parseLRCLines(lrc)
+
+	body := []byte{id3TextEncodingUTF8}
+	body = append(body, []byte(language)...)
+	body = append(body, 0x02) // timestamp format: 2 = absolute milliseconds
+	body = append(body, 0x01) // content type: 1 = lyrics
+	body = append(body, nullTerminatedUTF8("")...)
+
+	for _, line := range lines {
+		body = append(body, nullTerminatedUTF8(line.Text)...)
+		var ts [4]byte
+		binary.BigEndian.PutUint32(ts[:], uint32(line.Millis))
+		body = append(body, ts[:]...)
+	}
+	return body
+}
+
+// buildAPICFrame builds an attached-picture frame carrying cover art.
+// pictureType 3 is "Cover (front)", the value every player expects for a
+// track/mix thumbnail.
+func buildAPICFrame(mimeType string, pictureType byte, data []byte) []byte {
+	body := []byte{id3TextEncodingUTF8}
+	body = append(body, []byte(mimeType)...)
+	body = append(body, 0x00) // MIME type is ISO-8859-1 and null-terminated regardless of the frame's text encoding
+	body = append(body, pictureType)
+	body = append(body, nullTerminatedUTF8("")...) // description
+	body = append(body, data...)
+	return body
+}
+
+// buildCHAPFrame builds a chapter frame (ID3v2 Chapter Frame Addendum),
+// embedding a TIT2 sub-frame for the chapter title so players that render
+// CHAP frames (foobar2000, VLC) show a readable chapter list.
+func buildCHAPFrame(elementID string, startMs, endMs uint32, title string) []byte {
+	body := nullTerminatedUTF8Ascii(elementID)
+
+	var times [16]byte
+	binary.BigEndian.PutUint32(times[0:4], startMs)
+	binary.BigEndian.PutUint32(times[4:8], endMs)
+	binary.BigEndian.PutUint32(times[8:12], 0xFFFFFFFF) // start byte offset: unknown
+	binary.BigEndian.PutUint32(times[12:16], 0xFFFFFFFF)
+	body = append(body, times[:]...)
+
+	titleFrame := id3Frame{ID: "TIT2", Body: buildTextFrame(title)}
+	var header [10]byte
+	copy(header[0:4], titleFrame.ID)
+	size := encodeSynchsafe32(len(titleFrame.Body))
+	copy(header[4:8], size[:])
+	body = append(body, header[:]...)
+	body = append(body, titleFrame.Body...)
+
+	return body
+}
+
+// buildCTOCFrame builds the top-level table-of-contents frame that lists the
+// CHAP element IDs in order, so players know chapters exist and how they're
+// ordered.
+func buildCTOCFrame(elementID string, childElementIDs []string) []byte {
+	body := nullTerminatedUTF8Ascii(elementID)
+	body = append(body, 0x03) // flags: top-level (bit 1) + ordered (bit 0)
+	body = append(body, byte(len(childElementIDs)))
+	for _, id := range childElementIDs {
+		body = append(body, nullTerminatedUTF8Ascii(id)...)
+	}
+	return body
+}
+
+// nullTerminatedUTF8Ascii is used for ID3's plain (non-text-encoded) fields,
+// like CHAP/CTOC element IDs, which are always ISO-8859-1/ASCII.
+func nullTerminatedUTF8Ascii(s string) []byte {
+	return append([]byte(s), 0x00)
+}
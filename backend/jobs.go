@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+)
+
+// JobStage is one coarse phase of a long-running job, reported via
+// JobEvent.Stage so a client can render a progress bar without parsing
+// free-form messages.
+type JobStage string
+
+const (
+	JobStageQueued    JobStage = "queued"
+	JobStageAnalyzing JobStage = "analyzing"
+	JobStageRendering JobStage = "rendering"
+	JobStageMuxing    JobStage = "muxing"
+	JobStageDone      JobStage = "done"
+	JobStageError     JobStage = "error"
+	JobStageCancelled JobStage = "cancelled"
+)
+
+// JobEvent is one SSE payload on a job's GET /jobs/{id}/events stream: Stage
+// is the coarse phase, Progress is 0-100 within that stage (0 when not
+// meaningful), Message is the free-form detail ("analyzing track 3/10",
+// "ffmpeg encoding: 45%"), and Result carries the endpoint's normal response
+// body (AnalyzeResponse/RenderPreviewResponse/RenderMixResponse) once Stage
+// reaches done or error.
+type JobEvent struct {
+	Stage    JobStage    `json:"stage"`
+	Progress float64     `json:"progress,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+}
+
+// JobCreateResponse is the immediate reply to an async (no ?sync=1)
+// /analyze, /render/preview, or /render/mix request: just enough to start
+// watching GET /jobs/{id}/events or cancel via DELETE /jobs/{id}.
+type JobCreateResponse struct {
+	JobID string `json:"job_id"`
+}
+
+// job is one in-flight (or finished) async /analyze, /render/preview, or
+// /render/mix request. Modeled on StreamMount in stream.go — a package-level
+// registry behind a mutex, broadcasting events to every connected SSE
+// listener — covering all three endpoints under one /jobs/{id} namespace
+// (replacing the /render/mix-only job registry this once shared the tree
+// with), and adding real cancellation via cancel so DELETE /jobs/{id} can
+// stop work still in progress rather than just hiding its eventual result.
+type job struct {
+	mu        sync.Mutex
+	ID        string
+	cancel    context.CancelFunc
+	listeners map[int]chan JobEvent
+	nextID    int
+	done      bool
+	final     JobEvent
+}
+
+var jobsMu sync.Mutex
+var jobs = map[string]*job{}
+
+// newJob registers a job and returns it alongside a context that ctx.Done()s
+// when DELETE /jobs/{id} cancels it.
+func newJob() (*job, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{ID: randHex(8), cancel: cancel, listeners: map[int]chan JobEvent{}}
+	jobsMu.Lock()
+	jobs[j.ID] = j
+	jobsMu.Unlock()
+	return j, ctx
+}
+
+func getJob(id string) *job {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	return jobs[id]
+}
+
+// addListener registers a new SSE listener, unless the job already reached
+// its terminal event — in which case it hands that event back directly
+// instead of a channel, since nothing more will ever be broadcast.
+func (j *job) addListener() (id int, ch chan JobEvent, final *JobEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done {
+		ev := j.final
+		return 0, nil, &ev
+	}
+	j.nextID++
+	id = j.nextID
+	ch = make(chan JobEvent, 64)
+	j.listeners[id] = ch
+	return id, ch, nil
+}
+
+func (j *job) removeListener(id int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if ch, ok := j.listeners[id]; ok {
+		close(ch)
+		delete(j.listeners, id)
+	}
+}
+
+// emit fans ev out to every connected listener, dropping it for any listener
+// whose buffer is currently full instead of blocking the job on one slow
+// client (the same tradeoff as StreamMount.broadcast). A done/error/cancelled
+// event latches as j.final so late subscribers can still learn the outcome.
+func (j *job) emit(ev JobEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if ev.Stage == JobStageDone || ev.Stage == JobStageError || ev.Stage == JobStageCancelled {
+		j.final = ev
+		j.done = true
+	}
+	for _, ch := range j.listeners {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// cancelJob cancels j's context and emits a terminal cancelled event.
+// For /analyze this genuinely stops work: ctx is threaded into AnalyzeBatch
+// and, via exec.CommandContext, into the ffmpeg decode subprocess of
+// whichever file is currently being analyzed. /render/preview and
+// /render/mix jobs don't thread ctx into RenderPreview/RenderFinalMix (both
+// predate this file and don't accept one), so cancelling one of those just
+// stops the job from reporting further progress — the underlying ffmpeg
+// render keeps going in the background until it finishes on its own.
+func cancelJob(id string) bool {
+	j := getJob(id)
+	if j == nil {
+		return false
+	}
+	j.cancel()
+	j.emit(JobEvent{Stage: JobStageCancelled, Message: "cancelled"})
+	return true
+}
+
+// handleJobEvents handles GET /jobs/{id}/events, streaming j's JobEvents as
+// Server-Sent Events until a terminal done/error/cancelled event.
+func handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	j := getJob(r.PathValue("id"))
+	if j == nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	id, ch, final := j.addListener()
+	if final != nil {
+		writeJobEventSSE(w, *final)
+		flusher.Flush()
+		return
+	}
+	defer j.removeListener(id)
+
+	for ev := range ch {
+		writeJobEventSSE(w, ev)
+		flusher.Flush()
+		if ev.Stage == JobStageDone || ev.Stage == JobStageError || ev.Stage == JobStageCancelled {
+			return
+		}
+	}
+}
+
+func writeJobEventSSE(w http.ResponseWriter, ev JobEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Stage, data)
+}
+
+// handleJobCancel handles DELETE /jobs/{id} — see cancelJob for exactly what
+// cancellation does and doesn't stop.
+func handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if !cancelJob(r.PathValue("id")) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartAnalyzeJob kicks off AnalyzeBatch in a goroutine wired to a fresh
+// job's event stream, reporting "analyzing track N/total" as each file
+// finishes — the default (non-?sync=1) behavior of POST /analyze.
+func StartAnalyzeJob(req AnalyzeRequest, cacheDir string, loudnessCfg LoudnessConfig) string {
+	j, ctx := newJob()
+	total := len(req.Filepaths)
+	go func() {
+		j.emit(JobEvent{Stage: JobStageAnalyzing, Message: fmt.Sprintf("analyzing 0/%d", total)})
+		onProgress := func(done, total int, path string) {
+			j.emit(JobEvent{
+				Stage:    JobStageAnalyzing,
+				Progress: 100 * float64(done) / float64(total),
+				Message:  fmt.Sprintf("analyzing track %d/%d: %s", done, total, filepath.Base(path)),
+			})
+		}
+		results, errs := AnalyzeBatch(ctx, req.Filepaths, cacheDir, req.SkipDSPWhenTagged, loudnessCfg, onProgress)
+		if ctx.Err() != nil {
+			return // cancelJob already emitted the terminal event
+		}
+		j.emit(JobEvent{Stage: JobStageDone, Progress: 100, Result: AnalyzeResponse{Results: results, Errors: errs}})
+	}()
+	return j.ID
+}
+
+// StartRenderPreviewJob kicks off RenderPreview in a goroutine wired to a
+// fresh job's event stream — the default (non-?sync=1) behavior of
+// POST /render/preview. RenderPreview has no internal progress reporting, so
+// this only ever emits a "rendering" event up front and a terminal one.
+func StartRenderPreviewJob(req RenderPreviewRequest, cacheDir string) string {
+	j, _ := newJob()
+	go func() {
+		j.emit(JobEvent{Stage: JobStageRendering, Message: "rendering preview"})
+		outPath, err := RenderPreview(req.TrackAPath, req.TrackBPath, req.Spec, req.TrackAGain, req.TrackBGain, cacheDir)
+		if err != nil {
+			j.emit(JobEvent{Stage: JobStageError, Message: err.Error(), Result: RenderPreviewResponse{Error: err.Error()}})
+			return
+		}
+		j.emit(JobEvent{Stage: JobStageDone, Progress: 100, Result: RenderPreviewResponse{OutputPath: outPath}})
+	}()
+	return j.ID
+}
+
+// StartRenderMixEventJob kicks off RenderFinalMix in a goroutine wired to a
+// fresh job's event stream — the default (non-?sync=1) behavior of
+// POST /render/mix when StreamingFormat isn't set (that case is handled by
+// StartStreamingRenderMix instead). Reuses RenderFinalMix's existing
+// ProgressEvent channel, translated onto JobEvent's coarser shape for
+// GET /jobs/{id}/events.
+func StartRenderMixEventJob(req RenderMixRequest, cacheDir string) string {
+	j, _ := newJob()
+	progress := make(chan ProgressEvent, 16)
+	go func() {
+		for ev := range progress {
+			j.emit(renderProgressToJobEvent(ev))
+		}
+	}()
+	go func() {
+		defer close(progress)
+		_, _, _, _, _, err := RenderFinalMix(req.Playlist, req.Transitions, req.OutputPath, cacheDir, req.TargetLUFS, req.TargetTruePeakDB, req.FilterPreset, req.FilterChain, req.LoudnessMode, nil, progress)
+		if err != nil {
+			log.Printf("render job %s: %v", j.ID, err)
+		}
+	}()
+	return j.ID
+}
+
+// renderProgressToJobEvent maps RenderFinalMix's granular ProgressEvent
+// types onto JobEvent's coarser {stage, progress, message} shape. "done" and
+// "error" are ProgressEvent's own terminal types (see RenderFinalMix's
+// deferred emitProgress call), so they're the only ones that need to carry a
+// Result.
+func renderProgressToJobEvent(ev ProgressEvent) JobEvent {
+	switch ev.Type {
+	case "done":
+		return JobEvent{Stage: JobStageDone, Progress: 100, Result: RenderMixResponse{MP3Path: ev.MP3Path, LRCPath: ev.LRCPath, CUEPath: ev.CUEPath}}
+	case "error":
+		return JobEvent{Stage: JobStageError, Message: ev.Error, Result: RenderMixResponse{Error: ev.Error}}
+	case "encode_progress":
+		pct := 0.0
+		if ev.TotalSeconds > 0 {
+			pct = 100 * ev.EncodedSeconds / ev.TotalSeconds
+		}
+		return JobEvent{Stage: JobStageMuxing, Progress: pct, Message: fmt.Sprintf("ffmpeg encoding: %.0f%%", pct)}
+	default: // norm_start, norm_done, chunk_start, chunk_done
+		return JobEvent{Stage: JobStageRendering, Message: fmt.Sprintf("%s: %s", ev.Type, ev.TrackName)}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// hlsSegmentSeconds mirrors renderstream.go's streamSegmentSeconds — the
+// same ~6s target duration, applied here to a batch/on-disk render instead
+// of a live in-memory one.
+const hlsSegmentSeconds = streamSegmentSeconds
+
+// hlsBuilder accumulates RenderFinalMix's flushed canvas into fMP4 media
+// segments and writes them straight to disk under dir, the same "CMAF in
+// HLS" muxing renderstream.go's renderJob uses for live streaming jobs (see
+// buildInitSegment/buildMediaSegment/encodeAACSegment in fmp4.go and
+// renderstream.go), but as a one-shot batch render rather than a live job
+// served from an in-memory registry.
+//
+// Everything here runs on RenderFinalMix's own calling goroutine — onFlush
+// is called synchronously from RenderFinalMix's per-track loop, and
+// drainProgress is only ever called from inside onFlush/finish — so despite
+// reading from progressCh (fed by RenderFinalMix's progress parameter) there
+// is no concurrent access and no locking is needed.
+type hlsBuilder struct {
+	dir       string
+	startTime time.Time
+
+	acc         segmentAccumulator // shared accumulate/encode/mux pipeline, see segmenter.go
+	initWritten bool
+
+	progressCh chan ProgressEvent
+
+	// boundaryMsForTrack holds, for each track index whose incoming
+	// transition changes tempo, the cumulative output position (ms) at
+	// which that track starts — drained from chunk_done progress events —
+	// so emitSegment can tell whether a given segment straddles it.
+	boundaryMsForTrack map[int]float64
+	transitionJSON     map[int]string
+
+	entries []hlsPlaylistEntry
+}
+
+type hlsPlaylistEntry struct {
+	seq             int
+	durSeconds      float64
+	programDateTime time.Time
+	discontinuity   bool
+	dateRangeID     string
+	dateRangeJSON   string
+}
+
+// RenderHLS renders playlist/transitions to a fresh subdirectory of outDir
+// named after a random hex session ID, producing a self-contained HLS
+// asset: init.mp4, a sequence of ~hlsSegmentSeconds fMP4 media segments, and
+// a VOD playlist.m3u8 — servable as a finished asset via handleHLSFile
+// rather than an in-memory live job.
+//
+// At any track boundary whose incoming TransitionSpec changes tempo
+// (SpeedA or SpeedB != 1), the segment containing that boundary is marked
+// with EXT-X-DISCONTINUITY (the timeline genuinely isn't contiguous once
+// playback speed changes), an EXT-X-PROGRAM-DATE-TIME, and an
+// EXT-X-DATERANGE carrying the TransitionSpec as JSON so a client can render
+// a live transition overlay. Segment filenames aren't reused across calls —
+// each RenderHLS call gets its own session subdirectory — so a client never
+// sees a stale segment left over from a previous render at the same mount.
+func RenderHLS(playlist []TrackEntry, transitions []TransitionSpec, outDir, cacheDir string) (session string, err error) {
+	session = randHex(8)
+	dir := filepath.Join(outDir, session)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("hls: mkdir: %w", err)
+	}
+
+	b := &hlsBuilder{
+		dir:                dir,
+		acc:                segmentAccumulator{sampleRate: 44100},
+		startTime:          time.Now(),
+		progressCh:         make(chan ProgressEvent, 64),
+		boundaryMsForTrack: map[int]float64{},
+		transitionJSON:     map[int]string{},
+	}
+
+	for i, t := range transitions {
+		trackIdx := i + 1
+		if trackIdx >= len(playlist) {
+			continue
+		}
+		speedChanged := (t.SpeedA > 0 && t.SpeedA != 1.0) || (t.SpeedB > 0 && t.SpeedB != 1.0)
+		if !speedChanged {
+			continue
+		}
+		if data, jsonErr := json.Marshal(t); jsonErr == nil {
+			b.transitionJSON[trackIdx] = string(data)
+		}
+	}
+
+	tmpMP3 := filepath.Join(cacheDir, "hls_tmp_"+session+".mp3")
+	_, _, _, _, _, err = RenderFinalMix(playlist, transitions, tmpMP3, cacheDir, 0, 0, "", nil, "", b.onFlush, b.progressCh)
+	os.Remove(tmpMP3)
+	os.Remove(strings.TrimSuffix(tmpMP3, filepath.Ext(tmpMP3)) + ".lrc")
+	os.Remove(strings.TrimSuffix(tmpMP3, filepath.Ext(tmpMP3)) + ".cue")
+	if err != nil {
+		return "", fmt.Errorf("hls: render: %w", err)
+	}
+
+	if err := b.finish(); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "playlist.m3u8"), []byte(b.renderPlaylist()), 0644); err != nil {
+		return "", fmt.Errorf("hls: write playlist: %w", err)
+	}
+
+	return session, nil
+}
+
+// drainProgress non-blockingly pulls every chunk_done event sent so far,
+// recording each track-with-a-tempo-change's starting position in the final
+// output timeline.
+func (b *hlsBuilder) drainProgress() {
+	for {
+		select {
+		case ev, ok := <-b.progressCh:
+			if !ok {
+				return
+			}
+			if ev.Type != "chunk_done" {
+				continue
+			}
+			trackIdx := ev.TrackIndex + 1
+			if _, tracked := b.transitionJSON[trackIdx]; tracked {
+				b.boundaryMsForTrack[trackIdx] = float64(ev.PrevActualChunkMs)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// onFlush is RenderFinalMix's flush callback: it hands canvas prefixes to
+// b.acc (segmenter.go) and AAC-encodes whatever segmentAccumulator.accumulate
+// says is ready, mirroring renderstream.go's renderJob.onFlush.
+func (b *hlsBuilder) onFlush(canvas []float32, sr, fromSample, toSample int) {
+	b.drainProgress()
+
+	for _, chunk := range b.acc.accumulate(canvas, sr, fromSample, toSample, hlsSegmentSeconds) {
+		if err := b.emitSegment(chunk); err != nil {
+			log.Printf("hls %s: emit segment: %v", filepath.Base(b.dir), err)
+			return
+		}
+	}
+}
+
+// finish flushes any leftover PCM shorter than one full segment as a final
+// partial segment. Called once RenderFinalMix returns.
+func (b *hlsBuilder) finish() error {
+	b.drainProgress()
+	leftover := b.acc.drain()
+	if len(leftover) == 0 {
+		return nil
+	}
+	return b.emitSegment(leftover)
+}
+
+// emitSegment AAC-encodes samples via b.acc (segmenter.go), writes the
+// resulting fMP4 media segment to disk, and appends its playlist entry —
+// marking EXT-X-DISCONTINUITY when a tracked tempo-changing track boundary
+// falls within this segment's [startMs, endMs) span.
+func (b *hlsBuilder) emitSegment(samples []float32) error {
+	frames, err := b.acc.encode(samples)
+	if err != nil {
+		return fmt.Errorf("hls: encode segment: %w", err)
+	}
+
+	startMs := float64(b.acc.decodeTimeSoFar) / float64(b.acc.sampleRate) * 1000
+
+	seq, seg, durSeconds := b.acc.apply(frames)
+	if !b.initWritten {
+		if err := os.WriteFile(filepath.Join(b.dir, "init.mp4"), b.acc.initSegment, 0644); err != nil {
+			return fmt.Errorf("hls: write init segment: %w", err)
+		}
+		b.initWritten = true
+	}
+
+	endMs := startMs + durSeconds*1000
+
+	if err := os.WriteFile(filepath.Join(b.dir, fmt.Sprintf("seg%d.m4s", seq)), seg, 0644); err != nil {
+		return fmt.Errorf("hls: write segment: %w", err)
+	}
+
+	entry := hlsPlaylistEntry{
+		seq:             seq,
+		durSeconds:      durSeconds,
+		programDateTime: b.startTime.Add(time.Duration(startMs) * time.Millisecond),
+	}
+	for trackIdx, boundaryMs := range b.boundaryMsForTrack {
+		if boundaryMs >= startMs && boundaryMs < endMs {
+			entry.discontinuity = true
+			entry.dateRangeID = fmt.Sprintf("transition-%d", trackIdx)
+			entry.dateRangeJSON = b.transitionJSON[trackIdx]
+			delete(b.boundaryMsForTrack, trackIdx)
+		}
+	}
+
+	b.entries = append(b.entries, entry)
+	return nil
+}
+
+// renderPlaylist builds the finished VOD HLS playlist: fMP4 segments
+// referenced via EXT-X-MAP, EXT-X-PROGRAM-DATE-TIME on every segment, and
+// EXT-X-DISCONTINUITY/EXT-X-DATERANGE wherever a tempo-changing transition
+// lands.
+func (b *hlsBuilder) renderPlaylist() string {
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-TARGETDURATION:")
+	sb.WriteString(fmt.Sprintf("%d\n", int(hlsSegmentSeconds)+1))
+	sb.WriteString("#EXT-X-MAP:URI=\"init.mp4\"\n")
+	for _, e := range b.entries {
+		sb.WriteString(fmt.Sprintf("#EXT-X-PROGRAM-DATE-TIME:%s\n", e.programDateTime.Format(time.RFC3339Nano)))
+		if e.discontinuity {
+			sb.WriteString(fmt.Sprintf("#EXT-X-DATERANGE:ID=\"%s\",START-DATE=\"%s\",X-TRANSITION-SPEC=%q\n",
+				e.dateRangeID, e.programDateTime.Format(time.RFC3339Nano), e.dateRangeJSON))
+			sb.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		sb.WriteString(fmt.Sprintf("#EXTINF:%.3f,\nseg%d.m4s\n", e.durSeconds, e.seq))
+	}
+	sb.WriteString("#EXT-X-ENDLIST\n")
+	return sb.String()
+}
+
+// RenderHLSRequest/RenderHLSResponse are POST /render/hls's payload.
+type RenderHLSRequest struct {
+	Playlist    []TrackEntry     `json:"playlist"`
+	Transitions []TransitionSpec `json:"transitions"`
+}
+
+type RenderHLSResponse struct {
+	Session     string `json:"session,omitempty"`
+	PlaylistURL string `json:"playlist_url,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// handleRenderHLS handles POST /render/hls: synchronously renders
+// req.Playlist/Transitions to an HLS asset under hlsOutputDir and returns
+// its session ID, servable at GET /hls/{session}/playlist.m3u8.
+func handleRenderHLS(w http.ResponseWriter, r *http.Request) {
+	var req RenderHLSRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	absCache, _ := filepath.Abs(cacheDir)
+	session, err := RenderHLS(req.Playlist, req.Transitions, hlsOutputDir, absCache)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		json.NewEncoder(w).Encode(RenderHLSResponse{Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(RenderHLSResponse{Session: session, PlaylistURL: fmt.Sprintf("/hls/%s/playlist.m3u8", session)})
+}
+
+// handleHLSFile handles GET /hls/{session}/{file...}, serving whatever
+// RenderHLS wrote to hlsOutputDir/{session}/ — a plain static file server,
+// since RenderHLS's output is already a finished, complete-on-disk asset
+// (contrast renderstream.go's renderJob, which serves a still-growing live
+// render from memory).
+func handleHLSFile(w http.ResponseWriter, r *http.Request) {
+	session := r.PathValue("session")
+	file := r.PathValue("file")
+	if strings.Contains(session, "..") || strings.Contains(file, "..") {
+		http.Error(w, "bad path", http.StatusBadRequest)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(hlsOutputDir, session, file))
+}
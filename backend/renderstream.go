@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// streamSegmentSeconds is the target duration RenderFinalMix's flushed
+// canvas prefixes are cut into before AAC encoding — the same order of
+// magnitude as a typical Icecast/HLS segment, chosen independently of
+// RenderFinalMix's own "safe to flush" boundaries (which follow track
+// crossfades, not a fixed clock).
+const streamSegmentSeconds = 6.0
+
+// renderJob tracks one in-progress (or finished) streaming render: the
+// fMP4 init segment, every numbered media segment produced so far, and the
+// growing .m3u8 playlist referencing them (kept for both "fmp4" and "hls").
+// Modeled on StreamMount in stream.go: a package-level registry behind a
+// mutex, with the render's own goroutine as the only writer.
+type renderJob struct {
+	mu sync.Mutex
+
+	ID     string
+	Format string // "fmp4" or "hls"
+
+	acc segmentAccumulator // shared accumulate/encode/mux pipeline, see segmenter.go
+
+	initSegment     []byte
+	segments        map[int][]byte
+	playlistEntries []string
+
+	done bool
+	err  error
+}
+
+var renderJobsMu sync.Mutex
+var renderJobs = map[string]*renderJob{}
+
+func newRenderJob(format string) *renderJob {
+	j := &renderJob{
+		ID:       randHex(8),
+		Format:   format,
+		acc:      segmentAccumulator{sampleRate: 44100},
+		segments: map[int][]byte{},
+	}
+	renderJobsMu.Lock()
+	renderJobs[j.ID] = j
+	renderJobsMu.Unlock()
+	return j
+}
+
+func getRenderJob(id string) *renderJob {
+	renderJobsMu.Lock()
+	defer renderJobsMu.Unlock()
+	return renderJobs[id]
+}
+
+// fail records a fatal error and marks the job done; once done, the HTTP
+// handlers below serve whatever segments exist and stop waiting for more.
+func (j *renderJob) fail(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.err = err
+	j.done = true
+}
+
+// onFlush is RenderFinalMix's flush callback: it hands canvas prefixes to
+// j.acc (segmenter.go) and AAC-encodes whatever segmentAccumulator.accumulate
+// says is ready. A single flush (e.g. the final post-mastering tail) may be
+// long enough to cut several segments at once.
+func (j *renderJob) onFlush(canvas []float32, sr, fromSample, toSample int) {
+	j.mu.Lock()
+	ready := j.acc.accumulate(canvas, sr, fromSample, toSample, streamSegmentSeconds)
+	j.mu.Unlock()
+
+	for _, chunk := range ready {
+		if err := j.emitSegment(chunk); err != nil {
+			j.fail(err)
+			return
+		}
+	}
+}
+
+// finish flushes any leftover PCM shorter than one full segment as a final
+// partial segment, then marks the job done. Called once RenderFinalMix's
+// goroutine returns, whether it returned an error or not.
+func (j *renderJob) finish(renderErr error) {
+	j.mu.Lock()
+	leftover := j.acc.drain()
+	j.mu.Unlock()
+
+	if len(leftover) > 0 {
+		if err := j.emitSegment(leftover); err != nil && renderErr == nil {
+			renderErr = err
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if renderErr != nil {
+		j.err = renderErr
+	}
+	j.done = true
+}
+
+// emitSegment AAC-encodes samples via ffmpeg, packs the result into one
+// fMP4 moof+mdat via fmp4.go's buildMediaSegment, and appends it (plus its
+// #EXTINF playlist entry, whatever the job's Format) to the job.
+func (j *renderJob) emitSegment(samples []float32) error {
+	frames, err := j.acc.encode(samples)
+	if err != nil {
+		return fmt.Errorf("render stream %s: encode segment: %w", j.ID, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	seq, seg, durSeconds := j.acc.apply(frames)
+	j.initSegment = j.acc.initSegment
+	j.segments[seq] = seg
+	j.playlistEntries = append(j.playlistEntries, fmt.Sprintf("#EXTINF:%.3f,\nseg%d.m4s", durSeconds, seq))
+	return nil
+}
+
+// encodeAACSegment shells out to ffmpeg to encode one chunk of interleaved
+// stereo float32 PCM to AAC, returning its ADTS frames parsed via
+// fmp4.go's parseADTSFrames — the same "pipe PCM in, read an encoded
+// elementary stream out" shape StreamLiveEncode already uses for live
+// mp3/ogg broadcast, just targeting AAC/ADTS instead.
+func encodeAACSegment(samples []float32, sr int) ([]aacFrame, error) {
+	buf := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+
+	args := []string{
+		"-v", "error",
+		"-f", "f32le", "-ar", fmt.Sprintf("%d", sr), "-ac", "2", "-i", "pipe:0",
+		"-c:a", "aac", "-b:a", "160k", "-f", "adts", "pipe:1",
+	}
+	cmd := exec.Command(ffmpegPath, args...)
+	hideWindow(cmd)
+	cmd.Stdin = bytes.NewReader(buf)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg aac encode: %w: %s", err, stderr.String())
+	}
+	return parseADTSFrames(stdout.Bytes())
+}
+
+// StartStreamingRenderMix kicks off RenderFinalMix in a goroutine wired to
+// a fresh renderJob's onFlush, and returns the job's ID immediately rather
+// than blocking for the whole render — the async counterpart to
+// handleRenderMix's normal synchronous call.
+func StartStreamingRenderMix(req RenderMixRequest, cacheDir string) string {
+	job := newRenderJob(req.StreamingFormat)
+	go func() {
+		_, _, _, _, _, err := RenderFinalMix(req.Playlist, req.Transitions, req.OutputPath, cacheDir, req.TargetLUFS, req.TargetTruePeakDB, req.FilterPreset, req.FilterChain, req.LoudnessMode, job.onFlush, nil)
+		if err != nil {
+			log.Printf("render stream %s: %v", job.ID, err)
+		}
+		job.finish(err)
+	}()
+	return job.ID
+}
+
+// handleRenderMixStreamFile handles GET /render/mix/stream/{job_id}/{file},
+// serving init.mp4, playlist.m3u8, or segN.m4s. A single {file} wildcard is
+// used (rather than one route per file type) because Go 1.22's ServeMux
+// pattern syntax requires {name} to occupy a whole path segment, so a
+// literal "seg" prefix can't share a segment with a wildcard number.
+func handleRenderMixStreamFile(w http.ResponseWriter, r *http.Request) {
+	job := getRenderJob(r.PathValue("job_id"))
+	if job == nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	file := r.PathValue("file")
+	switch {
+	case file == "init.mp4":
+		job.mu.Lock()
+		init := job.initSegment
+		job.mu.Unlock()
+		if init == nil {
+			http.Error(w, "init segment not ready yet", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write(init)
+
+	case file == "playlist.m3u8":
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Write([]byte(job.renderPlaylist()))
+
+	case strings.HasPrefix(file, "seg") && strings.HasSuffix(file, ".m4s"):
+		seqStr := strings.TrimSuffix(strings.TrimPrefix(file, "seg"), ".m4s")
+		seq, err := strconv.Atoi(seqStr)
+		if err != nil {
+			http.Error(w, "bad segment name", http.StatusBadRequest)
+			return
+		}
+		job.mu.Lock()
+		seg, ok := job.segments[seq]
+		job.mu.Unlock()
+		if !ok {
+			http.Error(w, "segment not ready yet", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "video/mp4") // fMP4 media segment, same content-type as init.mp4
+		w.Write(seg)
+
+	default:
+		http.Error(w, "unknown file", http.StatusNotFound)
+	}
+}
+
+// renderPlaylist builds the growing HLS playlist for job: fMP4 segments
+// referenced via EXT-X-MAP (the same "CMAF in HLS" shape Apple's HLS spec
+// has supported since iOS 10), with no EXT-X-ENDLIST until the render
+// goroutine has actually finished.
+func (j *renderJob) renderPlaylist() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-TARGETDURATION:")
+	b.WriteString(fmt.Sprintf("%d\n", int(streamSegmentSeconds)+1))
+	b.WriteString("#EXT-X-MAP:URI=\"init.mp4\"\n")
+	for _, entry := range j.playlistEntries {
+		b.WriteString(entry)
+		b.WriteString("\n")
+	}
+	if j.done {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.String()
+}
+
+// handleRenderMixStreaming answers a POST /render/mix whose StreamingFormat
+// is "fmp4"/"hls" by kicking off StartStreamingRenderMix and returning its
+// JobID right away; it does not render synchronously itself.
+func handleRenderMixStreaming(w http.ResponseWriter, req RenderMixRequest, cacheDir string) {
+	jobID := StartStreamingRenderMix(req, cacheDir)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RenderMixResponse{JobID: jobID})
+}
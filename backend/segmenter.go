@@ -0,0 +1,70 @@
+package main
+
+// segmentAccumulator implements the "accumulate flushed PCM, cut it into
+// fixed-length chunks, AAC-encode and fMP4-mux each one" pipeline shared by
+// renderstream.go's renderJob (live streaming) and renderhls.go's hlsBuilder
+// (batch HLS render) — both drive it from RenderFinalMix's onFlush callback,
+// one keeping segments in memory for an HTTP handler to serve, the other
+// writing them straight to disk.
+type segmentAccumulator struct {
+	sampleRate      int
+	pending         []float32
+	decodeTimeSoFar uint64
+	nextSeq         int
+	initSegment     []byte
+}
+
+// accumulate appends canvas[fromSample:toSample] to the pending buffer and
+// cuts off every segmentSeconds-long chunk now available, returning them in
+// order. A single flush may be long enough to cut more than one segment at
+// once.
+func (s *segmentAccumulator) accumulate(canvas []float32, sr int, fromSample, toSample int, segmentSeconds float64) [][]float32 {
+	s.sampleRate = sr
+	s.pending = append(s.pending, canvas[fromSample:toSample]...)
+	segLen := int(segmentSeconds*float64(sr)) * 2
+	var ready [][]float32
+	for len(s.pending) >= segLen {
+		chunk := make([]float32, segLen)
+		copy(chunk, s.pending[:segLen])
+		ready = append(ready, chunk)
+		s.pending = s.pending[segLen:]
+	}
+	return ready
+}
+
+// drain returns and clears any leftover PCM shorter than one full segment,
+// to be emitted as a final partial segment once the render is known to be
+// done.
+func (s *segmentAccumulator) drain() []float32 {
+	leftover := s.pending
+	s.pending = nil
+	return leftover
+}
+
+// encode AAC-encodes samples at s.sampleRate. It doesn't touch any of
+// segmentAccumulator's other state, so a caller that needs to avoid holding
+// a lock across the ffmpeg subprocess (renderJob, read concurrently by HTTP
+// handlers) can call this unlocked and only take the lock around apply.
+func (s *segmentAccumulator) encode(samples []float32) ([]aacFrame, error) {
+	return encodeAACSegment(samples, s.sampleRate)
+}
+
+// apply packs frames into one fMP4 moof+mdat via fmp4.go's
+// buildMediaSegment, building the shared init segment on first call, and
+// advances decodeTimeSoFar/nextSeq so segment numbering and tfdt timestamps
+// stay contiguous across calls.
+func (s *segmentAccumulator) apply(frames []aacFrame) (seq int, seg []byte, durSeconds float64) {
+	var durSamples uint32
+	for _, f := range frames {
+		durSamples += f.samplesPerAAC
+	}
+	if s.initSegment == nil {
+		s.initSegment = buildInitSegment(s.sampleRate, 2)
+	}
+	seq = s.nextSeq
+	s.nextSeq++
+	seg = buildMediaSegment(uint32(seq+1), frames, s.decodeTimeSoFar)
+	s.decodeTimeSoFar += uint64(durSamples)
+	durSeconds = float64(durSamples) / float64(s.sampleRate)
+	return seq, seg, durSeconds
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamListenerBufferSize bounds how many chunks a slow listener can fall
+// behind by before the broadcaster starts dropping data for it rather than
+// blocking every other listener — the same tradeoff a real Icecast relay
+// makes for its per-client ring buffers.
+const streamListenerBufferSize = 64
+
+// icyMetaIntBytes is the interval (in audio bytes) at which StreamTitle
+// metadata blocks are interleaved for clients that asked for Icy-MetaData;
+// 16000 is the long-standing SHOUTcast/Icecast default.
+const icyMetaIntBytes = 16000
+
+type streamListener struct {
+	id        int
+	data      chan []byte
+	wantsMeta bool
+}
+
+// StreamMount is one live Icecast-style mountpoint: a named broadcast that
+// any number of HTTP clients can connect to via handleStreamMount.
+type StreamMount struct {
+	mu         sync.Mutex
+	Name       string
+	Format     string // "mp3" or "ogg"
+	listeners  map[int]*streamListener
+	nextID     int
+	nowPlaying string // current "Artist - Title", rendered into ICY StreamTitle blocks
+
+	// currentTrack/upcomingTransition back GET /stream/status, updated by
+	// AdvanceTrack whenever a live mix moves on to its next track.
+	currentTrack       TrackEntry
+	upcomingTransition *TransitionSpec
+}
+
+var streamMountsMu sync.Mutex
+var streamMounts = map[string]*StreamMount{}
+
+func getOrCreateStreamMount(name, format string) *StreamMount {
+	streamMountsMu.Lock()
+	defer streamMountsMu.Unlock()
+	if m, ok := streamMounts[name]; ok {
+		return m
+	}
+	m := &StreamMount{Name: name, Format: format, listeners: make(map[int]*streamListener)}
+	streamMounts[name] = m
+	return m
+}
+
+func getStreamMount(name string) *StreamMount {
+	streamMountsMu.Lock()
+	defer streamMountsMu.Unlock()
+	return streamMounts[name]
+}
+
+func (m *StreamMount) addListener(wantsMeta bool) *streamListener {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	l := &streamListener{id: m.nextID, data: make(chan []byte, streamListenerBufferSize), wantsMeta: wantsMeta}
+	m.listeners[l.id] = l
+	return l
+}
+
+func (m *StreamMount) removeListener(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.listeners[id]; ok {
+		close(l.data)
+		delete(m.listeners, id)
+	}
+}
+
+// broadcast fans chunk out to every connected listener, dropping it for any
+// listener whose buffer is currently full instead of blocking the encoder
+// on one slow client.
+func (m *StreamMount) broadcast(chunk []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.listeners {
+		select {
+		case l.data <- chunk:
+		default:
+		}
+	}
+}
+
+// SetNowPlaying updates the mount's ICY StreamTitle, picked up by the next
+// metadata block interleaved into each listener's stream.
+func (m *StreamMount) SetNowPlaying(artist, title string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nowPlaying = fmt.Sprintf("%s - %s", artist, title)
+}
+
+// AdvanceTrack records entry as the mount's now-playing TrackEntry (exposed
+// via GET /stream/status) and upcoming as its next scheduled transition, and
+// updates the ICY StreamTitle to "artist - title" — falling back to entry's
+// bare filename (extension stripped) when the caller doesn't have tagged
+// artist/title for it.
+func (m *StreamMount) AdvanceTrack(entry TrackEntry, artist, title string, upcoming *TransitionSpec) {
+	m.mu.Lock()
+	m.currentTrack = entry
+	m.upcomingTransition = upcoming
+	m.mu.Unlock()
+
+	if title == "" {
+		title = strings.TrimSuffix(entry.Filename, filepath.Ext(entry.Filename))
+	}
+	m.SetNowPlaying(artist, title)
+}
+
+// ListenerCount returns the number of clients currently connected to m.
+func (m *StreamMount) ListenerCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.listeners)
+}
+
+// Status snapshots m's now-playing state for handleStreamStatus.
+func (m *StreamMount) Status() StreamStatusResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return StreamStatusResponse{
+		Track:              m.currentTrack,
+		UpcomingTransition: m.upcomingTransition,
+		Listeners:          len(m.listeners),
+	}
+}
+
+func (m *StreamMount) icyStreamTitleBlock() []byte {
+	m.mu.Lock()
+	text := fmt.Sprintf("StreamTitle='%s';", strings.ReplaceAll(m.nowPlaying, "'", ""))
+	m.mu.Unlock()
+
+	// ICY metadata blocks are length-prefixed in units of 16 bytes, padded
+	// with trailing nulls to fill the final unit.
+	for len(text)%16 != 0 {
+		text += "\x00"
+	}
+	lengthByte := byte(len(text) / 16)
+	return append([]byte{lengthByte}, []byte(text)...)
+}
+
+func mimeForStreamFormat(format string) string {
+	if format == "ogg" {
+		return "application/ogg"
+	}
+	return "audio/mpeg"
+}
+
+// handleStreamMount handles GET /stream/{mount}, where mount carries its
+// extension (e.g. "radio.mp3", "radio.ogg").
+func handleStreamMount(w http.ResponseWriter, r *http.Request) {
+	mountParam := r.PathValue("mount")
+	format := "mp3"
+	name := mountParam
+	switch {
+	case strings.HasSuffix(mountParam, ".ogg"):
+		format = "ogg"
+		name = strings.TrimSuffix(mountParam, ".ogg")
+	case strings.HasSuffix(mountParam, ".mp3"):
+		name = strings.TrimSuffix(mountParam, ".mp3")
+	}
+	serveStreamMount(w, r, name, format)
+}
+
+// handleStreamDefault handles GET /stream, the single canonical "live" mount
+// a browser or any Icecast-capable player can tune into without knowing a
+// mount name — equivalent to GET /stream/live.mp3, or /stream/live.ogg via
+// ?format=ogg. This is what turns djbot from a batch renderer into a live
+// "radio" station with one well-known URL.
+func handleStreamDefault(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "mp3"
+	}
+	serveStreamMount(w, r, "live", format)
+}
+
+// serveStreamMount serves mount name as a continuous HTTP stream,
+// interleaving ICY StreamTitle metadata when the client sends
+// "Icy-MetaData: 1" the way VLC and every Icecast client do — shared by
+// handleStreamMount and handleStreamDefault.
+func serveStreamMount(w http.ResponseWriter, r *http.Request, name, format string) {
+	mount := getStreamMount(name)
+	if mount == nil {
+		http.Error(w, "mount not found", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	wantsMeta := r.Header.Get("Icy-MetaData") == "1"
+	w.Header().Set("Content-Type", mimeForStreamFormat(format))
+	w.Header().Set("icy-name", name)
+	w.Header().Set("Cache-Control", "no-cache")
+	if wantsMeta {
+		w.Header().Set("icy-metaint", fmt.Sprintf("%d", icyMetaIntBytes))
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	listener := mount.addListener(wantsMeta)
+	defer mount.removeListener(listener.id)
+
+	bytesSinceMeta := 0
+	for {
+		select {
+		case chunk, ok := <-listener.data:
+			if !ok {
+				return
+			}
+			if wantsMeta {
+				writeWithICYMetadata(w, mount, chunk, &bytesSinceMeta)
+			} else {
+				w.Write(chunk)
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeWithICYMetadata writes chunk to w, splitting it at icy-metaint byte
+// boundaries to interleave a StreamTitle metadata block, exactly as a
+// SHOUTcast/Icecast source does for any client that asked for Icy-MetaData.
+func writeWithICYMetadata(w io.Writer, mount *StreamMount, chunk []byte, bytesSinceMeta *int) {
+	for len(chunk) > 0 {
+		remaining := icyMetaIntBytes - *bytesSinceMeta
+		if remaining > len(chunk) {
+			w.Write(chunk)
+			*bytesSinceMeta += len(chunk)
+			return
+		}
+		w.Write(chunk[:remaining])
+		w.Write(mount.icyStreamTitleBlock())
+		chunk = chunk[remaining:]
+		*bytesSinceMeta = 0
+	}
+}
+
+// StreamFinishedRender is the "broadcast the finished render" mode: it loops
+// an already-rendered mix file to mountName forever, pacing writes to
+// roughly real-time so late-joining listeners aren't blasted with the whole
+// file at once.
+func StreamFinishedRender(mountName, filePath, format string) error {
+	mount := getOrCreateStreamMount(mountName, format)
+	go func() {
+		const chunkSize = 4096
+		const assumedBitrateBps = 128000
+		interval := time.Duration(float64(chunkSize) / (assumedBitrateBps / 8) * float64(time.Second))
+
+		for {
+			f, err := os.Open(filePath)
+			if err != nil {
+				log.Printf("stream %s: open %s: %v", mountName, filePath, err)
+				return
+			}
+			buf := make([]byte, chunkSize)
+			for {
+				n, readErr := f.Read(buf)
+				if n > 0 {
+					chunk := make([]byte, n)
+					copy(chunk, buf[:n])
+					mount.broadcast(chunk)
+					time.Sleep(interval)
+				}
+				if readErr != nil {
+					break
+				}
+			}
+			f.Close()
+		}
+	}()
+	return nil
+}
+
+// LiveChunk is one unit of PCM fed to StreamLiveEncode for a live mix. Track
+// is non-nil only on the chunk where a new track starts playing, signaling
+// the mount to advance its GET /stream/status state and ICY StreamTitle;
+// Artist/Title/Upcoming are only meaningful alongside a non-nil Track.
+type LiveChunk struct {
+	PCM      []float32
+	Track    *TrackEntry
+	Artist   string
+	Title    string
+	Upcoming *TransitionSpec
+}
+
+// StreamLiveEncode is the "live plan — encode chunk by chunk" mode: it pipes
+// PCM chunks arriving on chunks (e.g. as the planner/renderer produces them
+// track by track) into an ffmpeg encoder targeting format, broadcasts the
+// encoded output to mountName as it's produced, and calls the mount's
+// AdvanceTrack whenever a chunk carries a new Track. It encodes with
+// libvorbis for "ogg" and libmp3lame for anything else.
+//
+// Wiring an actual live-planning loop to call this is left to the
+// render/plan callers — this function only owns the encode-and-broadcast
+// half of that pipeline.
+func StreamLiveEncode(mountName string, sr int, format string, bitrateKbps int, chunks <-chan LiveChunk) error {
+	mount := getOrCreateStreamMount(mountName, format)
+
+	kbps := bitrateKbps
+	if kbps <= 0 {
+		kbps = 128
+	}
+	args := []string{"-v", "error", "-f", "f32le", "-ar", fmt.Sprintf("%d", sr), "-ac", "1", "-i", "pipe:0"}
+	switch format {
+	case "ogg":
+		args = append(args, "-c:a", "libvorbis", "-b:a", fmt.Sprintf("%dk", kbps), "-f", "ogg", "pipe:1")
+	default:
+		format = "mp3"
+		args = append(args, "-c:a", "libmp3lame", "-b:a", fmt.Sprintf("%dk", kbps), "-f", "mp3", "pipe:1")
+	}
+
+	cmd := exec.Command(ffmpegPath, args...)
+	hideWindow(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("live encode: stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("live encode: stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("live encode: start ffmpeg: %w", err)
+	}
+
+	go func() {
+		for c := range chunks {
+			if c.Track != nil {
+				mount.AdvanceTrack(*c.Track, c.Artist, c.Title, c.Upcoming)
+			}
+			buf := make([]byte, len(c.PCM)*4)
+			for i, s := range c.PCM {
+				binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(s))
+			}
+			if _, err := stdin.Write(buf); err != nil {
+				log.Printf("live encode %s: write pcm: %v", mountName, err)
+				break
+			}
+		}
+		stdin.Close()
+	}()
+
+	go func() {
+		reader := bufio.NewReaderSize(stdout, 8192)
+		buf := make([]byte, 8192)
+		for {
+			n, err := reader.Read(buf)
+			if n > 0 {
+				chunk := make([]byte, n)
+				copy(chunk, buf[:n])
+				mount.broadcast(chunk)
+			}
+			if err != nil {
+				break
+			}
+		}
+		cmd.Wait()
+	}()
+
+	return nil
+}
+
+// StartStreamRequest starts broadcasting a finished render to a mount.
+type StartStreamRequest struct {
+	Mount    string `json:"mount"`
+	FilePath string `json:"file_path"`
+	Format   string `json:"format,omitempty"` // "mp3" (default) or "ogg"
+}
+
+type StartStreamResponse struct {
+	URL   string `json:"url"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleStartStream handles POST /stream/start, registering a mount and
+// kicking off StreamFinishedRender against the given file.
+func handleStartStream(w http.ResponseWriter, r *http.Request) {
+	var req StartStreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Mount == "" || req.FilePath == "" {
+		http.Error(w, "mount and file_path required", http.StatusBadRequest)
+		return
+	}
+	format := req.Format
+	if format == "" {
+		format = "mp3"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := StreamFinishedRender(req.Mount, req.FilePath, format); err != nil {
+		json.NewEncoder(w).Encode(StartStreamResponse{Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(StartStreamResponse{URL: fmt.Sprintf("/stream/%s.%s", req.Mount, format)})
+}
+
+// StreamStatusResponse is GET /stream/status's payload: enough for a player
+// UI to show now-playing info without parsing ICY metadata out of the audio
+// stream itself.
+type StreamStatusResponse struct {
+	Track              TrackEntry      `json:"track"`
+	UpcomingTransition *TransitionSpec `json:"upcoming_transition,omitempty"`
+	Listeners          int             `json:"listeners"`
+	Error              string          `json:"error,omitempty"`
+}
+
+// handleStreamStatus handles GET /stream/status?mount=name (default "live"),
+// returning the mount's current TrackEntry, upcoming transition, and
+// listener count.
+func handleStreamStatus(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("mount")
+	if name == "" {
+		name = "live"
+	}
+	mount := getStreamMount(name)
+
+	w.Header().Set("Content-Type", "application/json")
+	if mount == nil {
+		json.NewEncoder(w).Encode(StreamStatusResponse{Error: "mount not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(mount.Status())
+}
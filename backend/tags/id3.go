@@ -0,0 +1,307 @@
+package tags
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// ID3Reader reads ID3v2.2/2.3/2.4 tags from MP3 files, falling back to the
+// 128-byte ID3v1 trailer when no ID3v2 header is present.
+type ID3Reader struct{}
+
+func (ID3Reader) Read(path string) (*Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 10)
+	if _, err := f.Read(header); err != nil {
+		return nil, err
+	}
+
+	if string(header[0:3]) != "ID3" {
+		return readID3v1(path)
+	}
+
+	majorVersion := header[3]
+	tagSize := decodeSynchsafe32(header[6:10])
+
+	body := make([]byte, tagSize)
+	if _, err := f.Read(body); err != nil {
+		return nil, fmt.Errorf("id3: read tag body: %w", err)
+	}
+
+	t := &Tags{}
+	offset := 0
+	frameIDLen := 4
+	frameHeaderLen := 10
+	if majorVersion == 2 {
+		// ID3v2.2 uses 3-char frame IDs and 3-byte sizes.
+		frameIDLen = 3
+		frameHeaderLen = 6
+	}
+
+	for offset+frameHeaderLen <= len(body) {
+		id := string(body[offset : offset+frameIDLen])
+		if id == "" || id[0] == 0 {
+			break
+		}
+		var size int
+		if frameIDLen == 3 {
+			size = int(body[offset+3])<<16 | int(body[offset+4])<<8 | int(body[offset+5])
+		} else if majorVersion == 4 {
+			size = decodeSynchsafe32(body[offset+4 : offset+8])
+		} else {
+			size = int(binary.BigEndian.Uint32(body[offset+4 : offset+8]))
+		}
+		dataStart := offset + frameHeaderLen
+		dataEnd := dataStart + size
+		if size < 0 || dataEnd > len(body) {
+			break
+		}
+		frameData := body[dataStart:dataEnd]
+		applyID3Frame(t, normalizeFrameID(id), frameData)
+		offset = dataEnd
+	}
+
+	return t, nil
+}
+
+// normalizeFrameID maps ID3v2.2's 3-char frame IDs onto their v2.3/2.4
+// equivalents so applyID3Frame only needs to know one name per field.
+func normalizeFrameID(id string) string {
+	switch id {
+	case "TT2":
+		return "TIT2"
+	case "TP1":
+		return "TPE1"
+	case "TAL":
+		return "TALB"
+	case "TP2":
+		return "TPE2"
+	case "TRK":
+		return "TRCK"
+	case "TCO":
+		return "TCON"
+	case "TYE":
+		return "TYER"
+	case "TBP":
+		return "TBPM"
+	case "TKE":
+		return "TKEY"
+	case "PIC":
+		return "APIC"
+	case "TXX":
+		return "TXXX"
+	default:
+		return id
+	}
+}
+
+func applyID3Frame(t *Tags, id string, data []byte) {
+	switch id {
+	case "TIT2":
+		t.Title = decodeID3Text(data)
+	case "TPE1":
+		t.Artist = decodeID3Text(data)
+	case "TALB":
+		t.Album = decodeID3Text(data)
+	case "TPE2":
+		t.AlbumArtist = decodeID3Text(data)
+	case "TRCK":
+		t.TrackNo = parseIntLoose(decodeID3Text(data))
+	case "TCON":
+		t.Genre = decodeID3Text(data)
+	case "TYER", "TDRC":
+		year := decodeID3Text(data)
+		if len(year) >= 4 {
+			t.Year = parseIntLoose(year[:4])
+		}
+	case "TBPM":
+		t.EmbeddedBPM = parseBPM(decodeID3Text(data))
+	case "TKEY":
+		t.EmbeddedKey = decodeID3Text(data)
+	case "APIC":
+		t.EmbeddedCoverHash = hashAPICPicture(data)
+	case "TXXX":
+		desc, value := decodeID3TXXX(data)
+		if strings.EqualFold(desc, "replaygain_track_gain") {
+			t.ReplayGainTrackGain = parseReplayGainDB(value)
+		}
+	}
+}
+
+// decodeID3Text strips the leading text-encoding byte and decodes the rest
+// as ISO-8859-1, UTF-16 (with BOM), UTF-16BE, or UTF-8 per the encoding byte.
+func decodeID3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	text := decodeID3EncodedString(data[0], data[1:])
+	return strings.TrimRight(text, "\x00")
+}
+
+func decodeID3EncodedString(encoding byte, raw []byte) string {
+	switch encoding {
+	case 0x01: // UTF-16 with BOM
+		return decodeUTF16(raw, true)
+	case 0x02: // UTF-16BE, no BOM
+		return decodeUTF16(raw, false)
+	case 0x03: // UTF-8
+		return string(raw)
+	default: // 0x00: ISO-8859-1
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+}
+
+func decodeUTF16(raw []byte, hasBOM bool) string {
+	if len(raw) < 2 {
+		return ""
+	}
+	bigEndian := true
+	if hasBOM {
+		if raw[0] == 0xFF && raw[1] == 0xFE {
+			bigEndian = false
+		}
+		raw = raw[2:]
+	}
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		if bigEndian {
+			units = append(units, binary.BigEndian.Uint16(raw[i:i+2]))
+		} else {
+			units = append(units, binary.LittleEndian.Uint16(raw[i:i+2]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// decodeID3TXXX splits a TXXX frame body into its (description, value) pair.
+func decodeID3TXXX(data []byte) (string, string) {
+	if len(data) == 0 {
+		return "", ""
+	}
+	encoding := data[0]
+	rest := data[1:]
+	sep := findNullSeparator(rest, encoding)
+	if sep < 0 {
+		return decodeID3EncodedString(encoding, rest), ""
+	}
+	desc := decodeID3EncodedString(encoding, rest[:sep])
+	value := decodeID3EncodedString(encoding, rest[sep+nullWidth(encoding):])
+	return strings.TrimRight(desc, "\x00"), strings.TrimRight(value, "\x00")
+}
+
+func nullWidth(encoding byte) int {
+	if encoding == 0x01 || encoding == 0x02 {
+		return 2
+	}
+	return 1
+}
+
+func findNullSeparator(data []byte, encoding byte) int {
+	width := nullWidth(encoding)
+	for i := 0; i+width <= len(data); i += width {
+		isNull := true
+		for j := 0; j < width; j++ {
+			if data[i+j] != 0 {
+				isNull = false
+				break
+			}
+		}
+		if isNull {
+			return i
+		}
+	}
+	return -1
+}
+
+// hashAPICPicture extracts the picture payload from an APIC frame body and
+// returns its MD5 hex digest.
+func hashAPICPicture(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	encoding := data[0]
+	rest := data[1:]
+	mimeEnd := strings.IndexByte(string(rest), 0)
+	if mimeEnd < 0 {
+		return ""
+	}
+	rest = rest[mimeEnd+1:]
+	if len(rest) < 1 {
+		return ""
+	}
+	rest = rest[1:] // picture type byte
+	descEnd := findNullSeparator(rest, encoding)
+	if descEnd < 0 {
+		return ""
+	}
+	picture := rest[descEnd+nullWidth(encoding):]
+	if len(picture) == 0 {
+		return ""
+	}
+	sum := md5.Sum(picture)
+	return fmt.Sprintf("%x", sum)
+}
+
+// parseReplayGainDB parses ReplayGain tag values like "-6.20 dB".
+func parseReplayGainDB(raw string) float64 {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimSuffix(strings.TrimSpace(raw), "dB")
+	raw = strings.TrimSpace(raw)
+	var gain float64
+	if _, err := fmt.Sscanf(raw, "%f", &gain); err != nil {
+		return 0
+	}
+	return gain
+}
+
+func decodeSynchsafe32(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
+
+// readID3v1 reads the legacy fixed-width 128-byte tag trailer when no ID3v2
+// header is present — still common on older rips.
+func readID3v1(path string) (*Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() < 128 {
+		return &Tags{}, nil
+	}
+
+	buf := make([]byte, 128)
+	if _, err := f.ReadAt(buf, info.Size()-128); err != nil {
+		return &Tags{}, nil
+	}
+	if string(buf[0:3]) != "TAG" {
+		return &Tags{}, nil
+	}
+
+	trim := func(b []byte) string {
+		return strings.TrimRight(string(b), " \x00")
+	}
+
+	t := &Tags{
+		Title:  trim(buf[3:33]),
+		Artist: trim(buf[33:63]),
+		Album:  trim(buf[63:93]),
+	}
+	t.Year = parseIntLoose(trim(buf[93:97]))
+	return t, nil
+}
@@ -0,0 +1,133 @@
+package tags
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// MP4Reader reads iTunes-style metadata atoms from m4a/mp4 files:
+// moov/udta/meta/ilst, descending into each ilst child's "data" sub-atom for
+// the actual value.
+type MP4Reader struct{}
+
+func (MP4Reader) Read(path string) (*Tags, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	moov := findMP4Atom(data, "moov")
+	if moov == nil {
+		return &Tags{}, nil
+	}
+	udta := findMP4Atom(moov, "udta")
+	if udta == nil {
+		return &Tags{}, nil
+	}
+	meta := findMP4Atom(udta, "meta")
+	if meta == nil {
+		return &Tags{}, nil
+	}
+	// "meta" carries a 4-byte version/flags field before its children.
+	if len(meta) < 4 {
+		return &Tags{}, nil
+	}
+	ilst := findMP4Atom(meta[4:], "ilst")
+	if ilst == nil {
+		return &Tags{}, nil
+	}
+
+	t := &Tags{}
+	walkMP4Atoms(ilst, func(atomType string, body []byte) {
+		payload := mp4AtomDataPayload(body)
+		if payload == nil {
+			return
+		}
+		switch atomType {
+		case "\xa9nam":
+			t.Title = string(payload)
+		case "\xa9ART":
+			t.Artist = string(payload)
+		case "\xa9alb":
+			t.Album = string(payload)
+		case "aART":
+			t.AlbumArtist = string(payload)
+		case "\xa9gen":
+			t.Genre = string(payload)
+		case "\xa9day":
+			if len(payload) >= 4 {
+				t.Year = parseIntLoose(string(payload[:4]))
+			}
+		case "trkn":
+			// 8-byte struct: reserved(2) + track(2BE) + total(2) + reserved(2)
+			if len(payload) >= 4 {
+				t.TrackNo = int(binary.BigEndian.Uint16(payload[2:4]))
+			}
+		case "tmpo":
+			if len(payload) >= 2 {
+				t.EmbeddedBPM = float64(binary.BigEndian.Uint16(payload[0:2]))
+			}
+		case "covr":
+			sum := md5.Sum(payload)
+			t.EmbeddedCoverHash = fmt.Sprintf("%x", sum)
+		}
+	})
+
+	return t, nil
+}
+
+// findMP4Atom returns the body (everything past the 8-byte size+type header)
+// of the first top-level atom named name within data.
+func findMP4Atom(data []byte, name string) []byte {
+	var found []byte
+	walkMP4Atoms(data, func(atomType string, body []byte) {
+		if found == nil && atomType == name {
+			found = body
+		}
+	})
+	return found
+}
+
+// walkMP4Atoms iterates the size+type+body atoms at the top level of data,
+// calling fn for each. A 64-bit "largesize" extended header is handled since
+// some muxers emit one for the top-level "mdat" atom (harmless to skip
+// correctly even though we don't care about mdat here).
+func walkMP4Atoms(data []byte, fn func(atomType string, body []byte)) {
+	offset := 0
+	for offset+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		atomType := string(data[offset+4 : offset+8])
+		headerLen := 8
+
+		if size == 1 {
+			if offset+16 > len(data) {
+				break
+			}
+			size = int(binary.BigEndian.Uint64(data[offset+8 : offset+16]))
+			headerLen = 16
+		} else if size == 0 {
+			size = len(data) - offset
+		}
+
+		if size < headerLen || offset+size > len(data) {
+			break
+		}
+		fn(atomType, data[offset+headerLen:offset+size])
+		offset += size
+	}
+}
+
+// mp4AtomDataPayload descends into an ilst child atom (e.g. "\xa9nam") to
+// find its nested "data" atom and returns the raw value bytes past that
+// atom's 8-byte type-indicator/locale header.
+func mp4AtomDataPayload(body []byte) []byte {
+	var payload []byte
+	walkMP4Atoms(body, func(atomType string, inner []byte) {
+		if atomType == "data" && len(inner) >= 8 {
+			payload = inner[8:]
+		}
+	})
+	return payload
+}
@@ -0,0 +1,57 @@
+//go:build cgo && taglib
+
+package tags
+
+/*
+#cgo LDFLAGS: -ltag_c
+#include <tag_c.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// TaglibReader calls taglib's C binding via cgo instead of this package's
+// from-scratch parsers. It covers every container taglib itself supports
+// (far more than ID3Reader/VorbisCommentReader/MP4Reader combined) at the
+// cost of a cgo + libtag dependency, so it's opt-in via the "taglib" build
+// tag rather than the default.
+type TaglibReader struct{}
+
+func (TaglibReader) Read(path string) (*Tags, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	file := C.taglib_file_new(cpath)
+	if file == nil {
+		return nil, fmt.Errorf("taglib: failed to open %s", path)
+	}
+	defer C.taglib_file_free(file)
+
+	tag := C.taglib_file_tag(file)
+	if tag == nil {
+		return &Tags{}, nil
+	}
+
+	return &Tags{
+		Title:   C.GoString(C.taglib_tag_title(tag)),
+		Artist:  C.GoString(C.taglib_tag_artist(tag)),
+		Album:   C.GoString(C.taglib_tag_album(tag)),
+		Genre:   C.GoString(C.taglib_tag_genre(tag)),
+		TrackNo: int(C.taglib_tag_track(tag)),
+		Year:    int(C.taglib_tag_year(tag)),
+	}, nil
+}
+
+// init registers TaglibReader for every extension this package otherwise
+// handles with its from-scratch parsers, so builds compiled with -tags
+// taglib get the cgo backend transparently via ReadTags.
+func init() {
+	backend := TaglibReader{}
+	for ext := range defaultReader.byExt {
+		defaultReader.byExt[ext] = backend
+	}
+}
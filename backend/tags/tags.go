@@ -0,0 +1,106 @@
+// Package tags reads embedded metadata (title/artist/BPM/key/ReplayGain/...)
+// from audio files so the analyzer can populate TrackAnalysis without a full
+// DSP pass when the file is already well-tagged.
+package tags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tags holds the metadata fields AnalyzeTrack cares about. Every field is
+// the zero value when the underlying container didn't carry it.
+type Tags struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	TrackNo     int
+	Genre       string
+	Year        int
+
+	// EmbeddedCoverHash is an MD5 hex digest of the cover art payload (if
+	// any), not the art itself — mirrors analyzer.fileHash's style of
+	// carrying a content fingerprint rather than raw bytes in TrackAnalysis.
+	EmbeddedCoverHash string
+
+	// EmbeddedBPM/EmbeddedKey come from tag fields some DJ software writes
+	// (TBPM/TKEY, Vorbis BPM/INITIALKEY, iTunes tmpo, ...). AnalyzeTrack can
+	// trust these instead of running its own DSP when SkipDSPWhenTagged is
+	// set and both are present.
+	EmbeddedBPM float64
+	EmbeddedKey string
+
+	ReplayGainTrackGain float64
+}
+
+// TagReader reads embedded metadata from a single audio file.
+type TagReader interface {
+	Read(path string) (*Tags, error)
+}
+
+// ReadTags dispatches to the TagReader appropriate for path's extension. It
+// is the package's main entry point; callers that need a specific format's
+// reader directly (e.g. for tests) can construct one of the concrete
+// readers below instead.
+func ReadTags(path string) (*Tags, error) {
+	return defaultReader.Read(path)
+}
+
+// multiReader dispatches by file extension to a per-format TagReader.
+type multiReader struct {
+	byExt map[string]TagReader
+}
+
+var defaultReader = newMultiReader()
+
+func newMultiReader() *multiReader {
+	m := &multiReader{byExt: make(map[string]TagReader)}
+	id3 := &ID3Reader{}
+	vorbis := &VorbisCommentReader{}
+	mp4 := &MP4Reader{}
+
+	m.byExt["mp3"] = id3
+	m.byExt["flac"] = vorbis
+	m.byExt["ogg"] = vorbis
+	m.byExt["m4a"] = mp4
+	m.byExt["mp4"] = mp4
+	return m
+}
+
+func (m *multiReader) Read(path string) (*Tags, error) {
+	ext := strings.ToLower(path)
+	if i := strings.LastIndex(ext, "."); i >= 0 {
+		ext = ext[i+1:]
+	}
+	reader, ok := m.byExt[ext]
+	if !ok {
+		return nil, fmt.Errorf("tags: no reader registered for extension %q", ext)
+	}
+	return reader.Read(path)
+}
+
+// parseBPM parses a tag's raw BPM text (e.g. "128", "128.00") loosely,
+// returning 0 when it isn't a usable number rather than erroring — a
+// missing/garbled BPM tag just means EmbeddedBPM stays unset.
+func parseBPM(raw string) float64 {
+	var bpm float64
+	if _, err := fmt.Sscanf(strings.TrimSpace(raw), "%f", &bpm); err != nil {
+		return 0
+	}
+	return bpm
+}
+
+func parseIntLoose(raw string) int {
+	// Track numbers are sometimes written "3/12" (track/total); take the
+	// part before the slash.
+	raw = strings.TrimSpace(raw)
+	if i := strings.Index(raw, "/"); i >= 0 {
+		raw = raw[:i]
+	}
+	var n int
+	if _, err := fmt.Sscanf(raw, "%d", &n); err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// checkOpenPGPSignature verifies that sig is a valid detached OpenPGP
+// signature over signed, made by one of the keys in keyring. It is the one
+// piece of the yt-dlp verification chain that requires a key the operator
+// trusts in advance; SHA-256 checksum verification in downloadYtdlp does not
+// depend on this and stays mandatory regardless.
+func checkOpenPGPSignature(keyring io.Reader, signed io.Reader, sig []byte) error {
+	entities, err := openpgp.ReadArmoredKeyRing(keyring)
+	if err != nil {
+		return fmt.Errorf("parse pubkey: %w", err)
+	}
+	signer, err := openpgp.CheckDetachedSignature(entities, signed, bytes.NewReader(sig))
+	if err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	if signer == nil {
+		return fmt.Errorf("signature verification failed: no matching signer")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ytdlpVersionInfo is the persisted record of which yt-dlp build we last
+// installed. The version handlers below read it; startup does not consult it.
+type ytdlpVersionInfo struct {
+	Channel string `json:"channel"`
+	Version string `json:"version"`
+}
+
+func ytdlpVersionInfoPath(dir string) string {
+	return filepath.Join(dir, "version.json")
+}
+
+func loadYtdlpVersionInfo(dir string) (ytdlpVersionInfo, error) {
+	var info ytdlpVersionInfo
+	data, err := os.ReadFile(ytdlpVersionInfoPath(dir))
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+func saveYtdlpVersionInfo(dir string, info ytdlpVersionInfo) {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(ytdlpVersionInfoPath(dir), data, 0644)
+}
+
+// ytdlpBinaryVersion runs `<path> --version` and returns its trimmed output,
+// e.g. "2024.05.27".
+func ytdlpBinaryVersion(path string) (string, error) {
+	cmd := exec.Command(path, "--version")
+	hideWindow(cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// latestYtdlpVersion asks GitHub for the tag of the configured channel's
+// "latest" release without downloading the asset itself, by following the
+// redirect on .../releases/latest.
+func latestYtdlpVersion() (string, error) {
+	channel := ytdlpChannel
+	if channel == "" {
+		channel = "stable"
+	}
+	repo := ytdlpReleaseRepo[channel]
+	if repo == "" {
+		repo = ytdlpReleaseRepo["stable"]
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(fmt.Sprintf("https://github.com/%s/releases/latest", repo))
+	if err != nil {
+		return "", fmt.Errorf("GET releases/latest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return "", fmt.Errorf("releases/latest did not redirect")
+	}
+	// .../releases/tag/<version>
+	return filepath.Base(loc), nil
+}
+
+// YtdlpVersionResponse is the payload for GET /ytdlp/version.
+type YtdlpVersionResponse struct {
+	Channel string `json:"channel"`
+	Current string `json:"current,omitempty"`
+	Latest  string `json:"latest,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleGetYtdlpVersion reports the installed vs. latest-available version
+// for the current channel, so the UI can show "current: X, latest: Y
+// [update]" instead of yt-dlp silently auto-updating in the background.
+func handleGetYtdlpVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	resp := YtdlpVersionResponse{Channel: ytdlpChannel}
+	if info, err := loadYtdlpVersionInfo(ytdlpBinDir); err == nil {
+		resp.Current = info.Version
+	}
+	latest, err := latestYtdlpVersion()
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Latest = latest
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleUpdateYtdlp forces a fresh download of the configured channel/pin
+// into the managed bin dir, replacing whatever is currently installed.
+func handleUpdateYtdlp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if ytdlpBinDir == "" {
+		json.NewEncoder(w).Encode(YtdlpVersionResponse{Error: "no managed data dir configured"})
+		return
+	}
+	if err := downloadYtdlp(ytdlpBinDir); err != nil {
+		json.NewEncoder(w).Encode(YtdlpVersionResponse{Error: err.Error()})
+		return
+	}
+	setYtdlpPath(filepath.Join(ytdlpBinDir, ytdlpExeName()))
+
+	resp := YtdlpVersionResponse{Channel: ytdlpChannel}
+	if info, err := loadYtdlpVersionInfo(ytdlpBinDir); err == nil {
+		resp.Current = info.Version
+	}
+	json.NewEncoder(w).Encode(resp)
+}